@@ -0,0 +1,62 @@
+// fsmgen generates Go source of a state machine from a JSON definition,
+// see package gen. it suits go generate:
+//
+//	//go:generate go run github.com/shory152/fsm/cmd/fsmgen door.json
+//
+// which writes door_fsm.go. the package defaults to $GOPACKAGE.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shory152/fsm/gen"
+)
+
+func main() {
+	out := flag.String("o", "", "output file, default the definition file with _fsm.go for .json")
+	pkg := flag.String("pkg", "", "package name, default the one defined or $GOPACKAGE")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: fsmgen [-o file] [-pkg name] definition.json\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *out, *pkg); err != nil {
+		fmt.Fprintln(os.Stderr, "fsmgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out, pkg string) error {
+	f, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	d, err := gen.Load(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case pkg != "":
+		d.Package = pkg
+	case d.Package == "":
+		d.Package = os.Getenv("GOPACKAGE")
+	}
+	src, err := gen.Generate(d)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		out = strings.TrimSuffix(in, ".json") + "_fsm.go"
+	}
+	return os.WriteFile(out, src, 0o644)
+}
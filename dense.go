@@ -0,0 +1,104 @@
+package fsm
+
+// max entries of a dense table, machines of sparser states and events
+// are stepped by the maps.
+const denseMax = 1 << 20
+
+// transitions in flat arrays indexed by the state and the event. the
+// states with pushdown transitions or a sub-machine are not compiled.
+type denseTable struct {
+	minState State
+	minEvent Event
+	events   int
+	states   []*interState // by state - minState, nil if not compiled
+	next     []*transition // by (state-minState)*events + event-minEvent
+}
+
+// step by a dense transition table built from the configuration, which
+// is rebuilt when the configuration changes. events accepted only by an
+// otherwise transition, out of the range of the events configured, and
+// the states not compiled are still stepped by the maps.
+func (fsm *stateMachine) Compile() {
+	fsm.flag |= fsm_flag_compiled
+	fsm.dense = compile(fsm.states, fsm.anyNext)
+}
+
+// the dense table of states, which is empty if it is too large
+func compile(states map[State]*interState, anyNext map[Event]*anyTransition) *denseTable {
+	var minS, maxS State
+	var minE, maxE Event
+	hasS, hasE := false, false
+	addEvent := func(e Event) {
+		if !hasE {
+			minE, maxE, hasE = e, e, true
+		}
+		minE, maxE = min(minE, e), max(maxE, e)
+	}
+	for e := range anyNext {
+		addEvent(e)
+	}
+	for s, is := range states {
+		if !hasS {
+			minS, maxS, hasS = s, s, true
+		}
+		minS, maxS = min(minS, s), max(maxS, s)
+		for e := range is.next {
+			addEvent(e)
+		}
+	}
+	if !hasS || !hasE {
+		return &denseTable{}
+	}
+
+	nstates := int(maxS-minS) + 1
+	nevents := int(maxE-minE) + 1
+	if nstates*nevents > denseMax {
+		return &denseTable{}
+	}
+
+	d := &denseTable{minState: minS, minEvent: minE, events: nevents}
+	d.states = make([]*interState, nstates)
+	d.next = make([]*transition, nstates*nevents)
+	for s, is := range states {
+		if is.pdNext != nil || is.sub != nil {
+			continue
+		}
+		i := int(s - minS)
+		d.states[i] = is
+		row := d.next[i*nevents : (i+1)*nevents]
+		for j := range row {
+			e := minE + Event(j)
+			if t, ok := is.next[e]; ok {
+				row[j] = t
+			} else if at, ok := anyNext[e]; ok && !at.except[s] {
+				row[j] = at.transition
+			} else {
+				row[j] = is.otherwise
+			}
+		}
+	}
+	return d
+}
+
+// the state s and its transition on ev, nil if not compiled
+func (d *denseTable) lookup(s State, ev Event) (*interState, *transition) {
+	i := int(s - d.minState)
+	j := int(ev - d.minEvent)
+	if uint(i) >= uint(len(d.states)) || uint(j) >= uint(d.events) {
+		return nil, nil
+	}
+	is := d.states[i]
+	if is == nil {
+		return nil, nil
+	}
+	return is, d.next[i*d.events+j]
+}
+
+// the dense table of the current configuration, nil if the machine is
+// not compiled
+func (fsm *stateMachine) denseTable() *denseTable {
+	if fsm.dense == nil && fsm.flag&fsm_flag_compiled > 0 {
+		fsm.dense = compile(fsm.states, fsm.anyNext)
+	}
+	return fsm.dense
+}
@@ -0,0 +1,363 @@
+package fsm
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// states, transitions and final states of a machine, such as NFA, or
+// StepFSM and the others by Inspector
+type Automaton interface {
+	StartState() State
+	States() []State
+	Finals() []State
+	Transitions(s State) []Transition
+}
+
+// a deterministic machine of states numbered from the start state 0
+type dfa struct {
+	events []Event // in ascending order
+	// the next state by event index, and by otherwise at len(events),
+	// -1 if none
+	next  [][]int
+	final []bool
+	old   [][]State // the states of the machine determinized
+}
+
+// the DFA of a by subset construction, with the states of a each state
+// of it stands for. each state of the DFA is a set of the states of a,
+// reachable from the start state, and named by them. epsilon moves of
+// NFA and the otherwise transitions of StepFSM are followed. actions,
+// outputs and sub-machines are not taken, nor the pushdown transitions,
+// as if the stack never matches.
+func Determinize(a Automaton) (StepFSM, map[State][]State) {
+	d := subsets(a)
+	return d.machine(a), d.mapping()
+}
+
+// the minimal DFA of a by Hopcroft's algorithm, as Determinize. states
+// of a it does not tell apart are merged, a state accepting an event
+// differs from one not accepting it even if neither reaches a final
+// state, so that the minimal DFA accepts the same events as a.
+func Minimize(a Automaton) (StepFSM, map[State][]State) {
+	d := subsets(a).minimize()
+	return d.machine(a), d.mapping()
+}
+
+// the set of states as a key of a map
+func setKey(set []State) string {
+	var b strings.Builder
+	for _, s := range set {
+		b.WriteString(strconv.Itoa(int(s)))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+func subsets(a Automaton) *dfa {
+	next := make(map[State]map[Event][]State)
+	events := make(map[Event]bool)
+	for _, s := range a.States() {
+		for _, t := range a.Transitions(s) {
+			if t.Op != StackNone {
+				continue
+			}
+			if next[s] == nil {
+				next[s] = make(map[Event][]State)
+			}
+			next[s][t.Event] = append(next[s][t.Event], t.To)
+			events[t.Event] = true
+		}
+	}
+	final := make(map[State]bool)
+	for _, s := range a.Finals() {
+		final[s] = true
+	}
+	other, _ := a.(interface{ Otherwise(State) (State, bool) })
+	eps, _ := a.(interface{ Epsilons(State) []State })
+
+	d := &dfa{}
+	for ev := range events {
+		d.events = append(d.events, ev)
+	}
+	sort.Slice(d.events, func(i, j int) bool {
+		return d.events[i] < d.events[j]
+	})
+
+	// the set of states and the ones reached from them by epsilon moves
+	closure := func(set map[State]bool) []State {
+		var stack []State
+		for s := range set {
+			stack = append(stack, s)
+		}
+		for len(stack) > 0 && eps != nil {
+			s := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, e := range eps.Epsilons(s) {
+				if !set[e] {
+					set[e] = true
+					stack = append(stack, e)
+				}
+			}
+		}
+		states := make([]State, 0, len(set))
+		for s := range set {
+			states = append(states, s)
+		}
+		sortStates(states)
+		return states
+	}
+
+	ids := make(map[string]int)
+	add := func(set []State) int {
+		if len(set) == 0 {
+			return -1
+		}
+		key := setKey(set)
+		if id, ok := ids[key]; ok {
+			return id
+		}
+		id := len(d.old)
+		ids[key] = id
+		d.old = append(d.old, set)
+		return id
+	}
+
+	add(closure(map[State]bool{a.StartState(): true}))
+	for id := 0; id < len(d.old); id++ {
+		set := d.old[id]
+		row := make([]int, len(d.events)+1)
+		for i, ev := range d.events {
+			to := make(map[State]bool)
+			for _, s := range set {
+				if ns, ok := next[s][ev]; ok {
+					for _, n := range ns {
+						to[n] = true
+					}
+				} else if other != nil {
+					if n, ok := other.Otherwise(s); ok {
+						to[n] = true
+					}
+				}
+			}
+			row[i] = add(closure(to))
+		}
+		to := make(map[State]bool)
+		for _, s := range set {
+			if other == nil {
+				break
+			}
+			if n, ok := other.Otherwise(s); ok {
+				to[n] = true
+			}
+		}
+		row[len(d.events)] = add(closure(to))
+
+		accepting := false
+		for _, s := range set {
+			accepting = accepting || final[s]
+		}
+		d.next = append(d.next, row)
+		d.final = append(d.final, accepting)
+	}
+	return d
+}
+
+// the minimal DFA of d by Hopcroft's algorithm
+func (d *dfa) minimize() *dfa {
+	// a dead state is added for the events not accepted
+	n := len(d.next) + 1
+	dead := n - 1
+	k := len(d.events) + 1
+	delta := func(s, c int) int {
+		if s == dead || d.next[s][c] < 0 {
+			return dead
+		}
+		return d.next[s][c]
+	}
+	pre := make([][][]int, k)
+	for c := range pre {
+		pre[c] = make([][]int, n)
+		for s := 0; s < n; s++ {
+			t := delta(s, c)
+			pre[c][t] = append(pre[c][t], s)
+		}
+	}
+
+	block := make([]int, n)
+	var blocks [][]int
+	var finals, others []int
+	for s := 0; s < dead; s++ {
+		if d.final[s] {
+			finals = append(finals, s)
+		} else {
+			others = append(others, s)
+		}
+	}
+	var work []int
+	inWork := make(map[int]bool)
+	push := func(b int) {
+		if !inWork[b] {
+			inWork[b] = true
+			work = append(work, b)
+		}
+	}
+	for _, members := range [][]int{finals, others, {dead}} {
+		if len(members) == 0 {
+			continue
+		}
+		for _, s := range members {
+			block[s] = len(blocks)
+		}
+		push(len(blocks))
+		blocks = append(blocks, members)
+	}
+
+	marked := make([]bool, n)
+	count := make([]int, n)
+	for len(work) > 0 {
+		b := work[len(work)-1]
+		work = work[:len(work)-1]
+		inWork[b] = false
+		splitter := append([]int(nil), blocks[b]...)
+		for c := 0; c < k; c++ {
+			var touched, x []int
+			for _, t := range splitter {
+				for _, s := range pre[c][t] {
+					if marked[s] {
+						continue
+					}
+					marked[s] = true
+					x = append(x, s)
+					if count[block[s]] == 0 {
+						touched = append(touched, block[s])
+					}
+					count[block[s]]++
+				}
+			}
+			for _, y := range touched {
+				if count[y] < len(blocks[y]) {
+					var in, out []int
+					for _, s := range blocks[y] {
+						if marked[s] {
+							in = append(in, s)
+						} else {
+							out = append(out, s)
+						}
+					}
+					z := len(blocks)
+					blocks[y] = in
+					blocks = append(blocks, out)
+					for _, s := range out {
+						block[s] = z
+					}
+					if inWork[y] || len(out) <= len(in) {
+						push(z)
+					} else {
+						push(y)
+					}
+				}
+				count[y] = 0
+			}
+			for _, s := range x {
+				marked[s] = false
+			}
+		}
+	}
+
+	// number the blocks from the start one, and drop the dead one
+	ids := make([]int, len(blocks))
+	for i := range ids {
+		ids[i] = -1
+	}
+	m := &dfa{events: d.events}
+	var visit []int
+	number := func(b int) int {
+		if b == block[dead] {
+			return -1
+		}
+		if ids[b] < 0 {
+			ids[b] = len(visit)
+			visit = append(visit, b)
+		}
+		return ids[b]
+	}
+	number(block[0])
+	for i := 0; i < len(visit); i++ {
+		b := visit[i]
+		s := blocks[b][0]
+		row := make([]int, k)
+		for c := range row {
+			row[c] = number(block[delta(s, c)])
+		}
+		var old []State
+		for _, s := range blocks[b] {
+			old = append(old, d.old[s]...)
+		}
+		m.next = append(m.next, row)
+		m.final = append(m.final, d.final[s])
+		m.old = append(m.old, uniqueStates(old))
+	}
+	return m
+}
+
+// the states sorted, without duplicates
+func uniqueStates(states []State) []State {
+	sortStates(states)
+	n := 0
+	for i, s := range states {
+		if i == 0 || s != states[n-1] {
+			states[n] = s
+			n++
+		}
+	}
+	return states[:n]
+}
+
+// the machine of d, named by a if it is a Namer
+func (d *dfa) machine(a Automaton) StepFSM {
+	name := State.String
+	sm := newStateMachine(0)
+	sm.flag |= fsm_flag_step
+	if nm, ok := a.(Namer); ok {
+		name = nm.StateName
+		for _, ev := range d.events {
+			sm.NameEvent(ev, nm.EventName(ev))
+		}
+	}
+
+	other := len(d.events)
+	for s, row := range d.next {
+		cs := sm.ConfigState(State(s))
+		if d.final[s] {
+			cs.Final()
+		}
+		for i, ev := range d.events {
+			if row[i] >= 0 && row[i] != row[other] {
+				cs.Accept(ev, State(row[i]))
+			}
+		}
+		if row[other] >= 0 {
+			cs.Otherwise(State(row[other]))
+		}
+
+		names := make([]string, len(d.old[s]))
+		for i, o := range d.old[s] {
+			names[i] = name(o)
+		}
+		sm.NameState(State(s), "{"+strings.Join(names, ",")+"}")
+	}
+	return sm
+}
+
+// the states of d each state it stands for is in
+func (d *dfa) mapping() map[State][]State {
+	m := make(map[State][]State)
+	for s, old := range d.old {
+		for _, o := range old {
+			m[o] = append(m[o], State(s))
+		}
+	}
+	return m
+}
@@ -1,5 +1,10 @@
 package fsm
 
+import (
+	"fmt"
+	"sync/atomic"
+)
+
 // FSM's state
 type State int
 
@@ -18,85 +23,314 @@ func (f ActionFunc) Do() {
 	f()
 }
 
+// Action which receives the event triggering it
+type EventAction interface {
+	Do(e Event)
+}
+
+// EventAction func
+type EventActionFunc func(e Event)
+
+func (f EventActionFunc) Do(e Event) {
+	f(e)
+}
+
 // export ConfigState for configure each state of StateMachine
 type ConfigState interface {
 	Accept(e Event, next State) ConfigState
+	AcceptInternal(e Event, a Action) ConfigState
 	OnEnter(a Action) ConfigState
 	OnEnterFrom(prev State, a Action) ConfigState
 	OnExit(a Action) ConfigState
 	OnExitEvent(e Event, a Action) ConfigState
+	OnExitTo(next State, a Action) ConfigState
+	OnEnterVia(e Event, a Action) ConfigState
+	AddEnter(name string, prio int, a Action) ConfigState
+	AddEnterFrom(prev State, name string, prio int, a Action) ConfigState
+	AddExit(name string, prio int, a Action) ConfigState
+	AddExitEvent(e Event, name string, prio int, a Action) ConfigState
+	AddExitTo(next State, name string, prio int, a Action) ConfigState
+	AddEnterVia(e Event, name string, prio int, a Action) ConfigState
+	RemoveAction(name string) ConfigState
+	Specific(p HookPolicy) ConfigState
+	Otherwise(next State) ConfigState
+	OnOtherwise(a EventAction) ConfigState
+	AcceptPush(e Event, sym Symbol, next State) ConfigState
+	AcceptPop(e Event, sym Symbol, next State) ConfigState
+	AcceptIfTop(e Event, sym Symbol, next State) ConfigState
+	AcceptCall(e Event, sub State, ret State) ConfigState
+	AcceptReturn(e Event) ConfigState
+	Submachine(child StepFSM, done State, final ...State) ConfigState
+	AutoSubmachine(child AutoFSM, start Event, done State, final ...State) ConfigState
+	Final() ConfigState
+	Output(out any) ConfigState
+	AcceptOutput(e Event, next State, out any) ConfigState
+	OnEnterErr(a ErrAction) ConfigState
+	OnExitErr(a ErrAction) ConfigState
+	AcceptAction(e Event, next State, a ErrAction) ConfigState
+	OnEnterFail(p FailPolicy, errState ...State) ConfigState
+}
+
+// a transition of a state
+type transition struct {
+	to        *interState // nil for AcceptReturn
+	action    EventAction
+	errAction ErrAction
+	op        StackOp
+	sym       Symbol
+	ret       *interState // return state of AcceptCall
+	out       any         // Mealy output
+	internal  bool        // neither exit nor enter the state
 }
 
 type interState struct {
 	id          State
-	enterAction Action
-	enterFrom   map[State]Action
-	exitAction  Action
-	exitFrom    map[Event]Action
-	next        map[Event]*interState
+	enterAction hooks
+	enterFrom   map[State]hooks
+	exitAction  hooks
+	exitFrom    map[Event]hooks
+	exitTo      map[State]hooks
+	enterVia    map[Event]hooks
+	hookPolicy  HookPolicy
+	next        map[Event]*transition
+	pdNext      map[Event][]*transition
+	otherwise   *transition
+	otherAction EventAction
+	sub         *subMachine
+	final       bool
+	output      any
+	failPolicy  FailPolicy
+	errState    *interState
 	fsm         *stateMachine
 }
 
 // this state accept e, then transfer to nextS
 func (is *interState) Accept(e Event, nextS State) ConfigState {
+	is.fsm.configurable()
 	nis := is.fsm.ConfigState(nextS)
 	if is.next == nil {
-		is.next = make(map[Event]*interState)
+		is.next = make(map[Event]*transition)
 	}
-	is.next[e] = nis.(*interState)
+	is.next[e] = &transition{to: nis.(*interState)}
 	return is
 }
 
-// execute act when enter this state
+// this state accept e and execute act, staying in this state without
+// exiting and entering it again as Accept(e, this state) does.
+func (is *interState) AcceptInternal(e Event, act Action) ConfigState {
+	is.fsm.configurable()
+	if is.next == nil {
+		is.next = make(map[Event]*transition)
+	}
+	is.next[e] = &transition{to: is, errAction: actionOf(act), internal: true}
+	return is
+}
+
+// execute act when enter this state, it replaces the previous one set
+// by OnEnter, but not the ones added by AddEnter.
 func (is *interState) OnEnter(act Action) ConfigState {
-	is.enterAction = act
+	is.fsm.configurable()
+	is.enterAction = is.enterAction.setDefault(actionOf(act))
 	return is
 }
 
 // execute act when enter this state from prev
 func (is *interState) OnEnterFrom(prev State, act Action) ConfigState {
+	is.fsm.configurable()
 	if is.enterFrom == nil {
-		is.enterFrom = make(map[State]Action)
+		is.enterFrom = make(map[State]hooks)
 	}
-	is.enterFrom[prev] = act
+	is.enterFrom[prev] = is.enterFrom[prev].setDefault(actionOf(act))
 	return is
 }
 
 // execute act when exit this state
 func (is *interState) OnExit(act Action) ConfigState {
-	is.exitAction = act
+	is.fsm.configurable()
+	is.exitAction = is.exitAction.setDefault(actionOf(act))
 	return is
 }
 
 // execute act when exit this state triggered by Event e
 func (is *interState) OnExitEvent(e Event, act Action) ConfigState {
-	is.exitFrom[e] = act
+	is.fsm.configurable()
+	if is.exitFrom == nil {
+		is.exitFrom = make(map[Event]hooks)
+	}
+	is.exitFrom[e] = is.exitFrom[e].setDefault(actionOf(act))
+	return is
+}
+
+// execute act when exit this state to next
+func (is *interState) OnExitTo(next State, act Action) ConfigState {
+	is.fsm.configurable()
+	if is.exitTo == nil {
+		is.exitTo = make(map[State]hooks)
+	}
+	is.exitTo[next] = is.exitTo[next].setDefault(actionOf(act))
+	return is
+}
+
+// execute act when enter this state triggered by Event e
+func (is *interState) OnEnterVia(e Event, act Action) ConfigState {
+	is.fsm.configurable()
+	if is.enterVia == nil {
+		is.enterVia = make(map[Event]hooks)
+	}
+	is.enterVia[e] = is.enterVia[e].setDefault(actionOf(act))
+	return is
+}
+
+// transfer to next when this state can not accept an event
+func (is *interState) Otherwise(next State) ConfigState {
+	is.fsm.configurable()
+	is.otherwise = &transition{to: is.fsm.ConfigState(next).(*interState)}
+	is.otherwise.action = is.otherAction
+	return is
+}
+
+// execute act with the arrived event when the otherwise transition
+// is taken, after exiting this state and before entering the next.
+func (is *interState) OnOtherwise(act EventAction) ConfigState {
+	is.fsm.configurable()
+	is.otherAction = act
+	if is.otherwise != nil {
+		is.otherwise.action = act
+	}
+	return is
+}
+
+// this state accept e, then transfer to nextS with the Mealy output out
+func (is *interState) AcceptOutput(e Event, nextS State, out any) ConfigState {
+	is.fsm.configurable()
+	is.Accept(e, nextS)
+	is.next[e].out = out
+	return is
+}
+
+// the Moore output of this state
+func (is *interState) Output(out any) ConfigState {
+	is.fsm.configurable()
+	is.output = out
+	return is
+}
+
+// mark this state as a final (accepting) state
+func (is *interState) Final() ConfigState {
+	is.fsm.configurable()
+	is.final = true
 	return is
 }
 
 // fsm which be driven step-by-step
 type StepFSM interface {
+	Inspector
+	Namer
 	ConfigState(State) ConfigState
-	Step(Event)
+	AcceptAny(e Event, next State)
+	AcceptAnyExcept(e Event, next State, except ...State)
+	Step(Event) any
+	TryStep(Event) (any, error)
+	Err() error
+	Recover()
+	RecoverTo(errState State)
+	Freeze()
+	Reconfigure(config func(b Builder)) error
+	Reset()
+	ResetTo(s State)
+	Compile()
+	Output() any
+	Top() (Symbol, bool)
+	Depth() int
+	IsAccepting() bool
+	Done() bool
 	Close()
 }
 
 // fsm which receive the first event, then run automatically.
 type AutoFSM interface {
+	Inspector
+	Namer
 	ConfigState(s State) ConfigState
+	AcceptAny(e Event, next State)
+	AcceptAnyExcept(e Event, next State, except ...State)
 	Feed(next Event)
 	Start(start Event)
 	Stop()
 	Pause(next Event)
 	Resume()
+	Err() error
+	Recover()
+	RecoverTo(errState State)
+	Freeze()
+	Reconfigure(config func(b Builder)) error
+	Reset()
+	ResetTo(s State)
+	Compile()
+	Top() (Symbol, bool)
+	Depth() int
+	IsAccepting() bool
+	Done() bool
 	Close()
 }
 
+// step sm by events, and return whether it ends in a final state, with
+// the states it goes through from the current one. it stops at the
+// first event which can not be accepted or whose step fails, and
+// returns false.
+func RunEvents(sm StepFSM, events ...Event) (bool, []State) {
+	fsm := sm.(*stateMachine)
+	path := []State{fsm.currentState}
+	for _, ev := range events {
+		if !fsm.CanAccept(ev) {
+			return false, path
+		}
+		if _, err := fsm.TryStep(ev); err != nil {
+			return false, path
+		}
+		path = append(path, fsm.currentState)
+	}
+	return fsm.IsAccepting(), path
+}
+
+// step sm by events, and return whether all are accepted, with the
+// outputs of the steps. it stops at the first event which can not be
+// accepted or whose step fails, and returns false.
+func Transduce(sm StepFSM, events ...Event) (bool, []any) {
+	fsm := sm.(*stateMachine)
+	outs := make([]any, 0, len(events))
+	for _, ev := range events {
+		if !fsm.CanAccept(ev) {
+			return false, outs
+		}
+		out, err := fsm.TryStep(ev)
+		if err != nil {
+			return false, outs
+		}
+		outs = append(outs, out)
+	}
+	return true, outs
+}
+
+// transition from any state (except some) on an event
+type anyTransition struct {
+	*transition
+	except map[State]bool
+}
+
 type stateMachine struct {
 	flag         uint32
 	nextEvent    Event
+	startState   State
 	currentState State
 	states       map[State]*interState
+	anyNext      map[Event]*anyTransition
+	stack        []stackEntry
+	lastErr      error
+	panicState   *interState
+	pending      atomic.Pointer[table]
+	dense        *denseTable
+	namer
 }
 
 const (
@@ -106,6 +340,9 @@ const (
 	fsm_flag_pause
 	fsm_flag_stopped
 	fsm_flag_nextev
+	fsm_flag_recover
+	fsm_flag_frozen
+	fsm_flag_compiled
 )
 
 func (fsm *stateMachine) isAutoFsm() bool {
@@ -126,15 +363,50 @@ func (fsm *stateMachine) isPaused() bool {
 func (fsm *stateMachine) isSetNextEv() bool {
 	return fsm.flag&fsm_flag_nextev > 0
 }
+func (fsm *stateMachine) isRecovering() bool {
+	return fsm.flag&fsm_flag_recover > 0
+}
+func (fsm *stateMachine) isFrozen() bool {
+	return fsm.flag&fsm_flag_frozen > 0
+}
 
 func newStateMachine(startState State) *stateMachine {
 	fsm := &stateMachine{}
 	fsm.states = make(map[State]*interState)
+	fsm.startState = startState
 	fsm.currentState = startState
 	//fsm.ConfigState(startState)
 	return fsm
 }
 
+// return to the start state, the configuration is kept
+func (fsm *stateMachine) reset() {
+	fsm.flag &= fsm_flag_auto | fsm_flag_step | fsm_flag_recover | fsm_flag_frozen | fsm_flag_compiled
+	fsm.currentState = fsm.startState
+	fsm.stack = fsm.stack[:0]
+	fsm.lastErr = nil
+}
+
+// return to the start state, and clear the stack, the event fed and the
+// error, a stopped machine can run again. the configuration is kept.
+// no action is executed, but the sub-machine of the state is restarted.
+func (fsm *stateMachine) Reset() {
+	fsm.ResetTo(fsm.startState)
+}
+
+// as Reset, but return to s
+func (fsm *stateMachine) ResetTo(s State) {
+	is, ok := fsm.states[s]
+	if !ok {
+		panic(fmt.Sprintf("no such state %v", fsm.StateName(s)))
+	}
+	fsm.reset()
+	fsm.currentState = s
+	if is.sub != nil {
+		is.sub.enter()
+	}
+}
+
 func NewStepFSM(startState State) StepFSM {
 	fsm := newStateMachine(startState)
 	fsm.flag |= fsm_flag_step
@@ -148,6 +420,7 @@ func NewAutoFSM(startState State) AutoFSM {
 }
 
 func (fsm *stateMachine) ConfigState(s State) ConfigState {
+	fsm.configurable()
 	if ss, ok := fsm.states[s]; ok {
 		return ss
 	} else {
@@ -159,43 +432,218 @@ func (fsm *stateMachine) ConfigState(s State) ConfigState {
 	}
 }
 
-// feed the Event ev to fsm, transfer to next state
-func (fsm *stateMachine) Step(ev Event) {
+// any state accept e, then transfer to next
+func (fsm *stateMachine) AcceptAny(e Event, next State) {
+	fsm.AcceptAnyExcept(e, next)
+}
+
+// any state except the given ones accept e, then transfer to next.
+// transitions configured by ConfigState.Accept take precedence.
+func (fsm *stateMachine) AcceptAnyExcept(e Event, next State, except ...State) {
+	fsm.configurable()
+	at := &anyTransition{}
+	at.transition = &transition{to: fsm.ConfigState(next).(*interState)}
+	if len(except) > 0 {
+		at.except = make(map[State]bool)
+		for _, s := range except {
+			at.except[s] = true
+		}
+	}
+	if fsm.anyNext == nil {
+		fsm.anyNext = make(map[Event]*anyTransition)
+	}
+	fsm.anyNext[e] = at
+}
+
+// find the transition of cs on ev, the state's own pushdown transitions
+// matching the stack first, then its other transitions, then the
+// wildcard ones, then the otherwise one.
+func (fsm *stateMachine) nextState(cs *interState, ev Event) (*transition, bool) {
+	for _, t := range cs.pdNext[ev] {
+		if fsm.matchStack(t) {
+			return t, true
+		}
+	}
+	if t, ok := cs.next[ev]; ok {
+		return t, true
+	}
+	if at, ok := fsm.anyNext[ev]; ok && !at.except[cs.id] {
+		return at.transition, true
+	}
+	if cs.otherwise != nil {
+		return cs.otherwise, true
+	}
+	return nil, false
+}
+
+// feed the Event ev to fsm, transfer to next state. it returns the
+// Mealy output of the transition, or the Moore output of the next state
+// if the transition has none. the output of the sub-machine is returned
+// when ev is forwarded to it. it panics if an action fails, see TryStep.
+func (fsm *stateMachine) Step(ev Event) any {
+	out, err := fsm.TryStep(ev)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// as Step, but the failure of an action is returned. the machine stays
+// in the current state if an exit or transition action fails, and the
+// policy set by OnEnterFail applies if an enter action fails. a panic
+// in an action is returned as a failure if the machine recovers, see
+// Recover.
+func (fsm *stateMachine) TryStep(ev Event) (any, error) {
+	fsm.lastErr = nil
+	if fsm.pending.Load() != nil {
+		if err := fsm.swap(fsm.pending.Swap(nil)); err != nil {
+			fsm.lastErr = err
+			return nil, err
+		}
+	}
+	out, err := fsm.step(ev)
+	if err != nil && fsm.panicState != nil && isPanic(err) {
+		cs := fsm.states[fsm.currentState]
+		out, err = fsm.enterErrState(cs, fsm.panicState, ev, err)
+	}
+	if err != nil {
+		fsm.lastErr = err
+	}
+	return out, err
+}
+
+func (fsm *stateMachine) step(ev Event) (any, error) {
+	if d := fsm.denseTable(); d != nil {
+		if cs, t := d.lookup(fsm.currentState, ev); t != nil {
+			return fsm.transit(cs, t, ev)
+		}
+	}
 	if currentState, ok := fsm.states[fsm.currentState]; !ok {
-		panic("no such state")
-	} else if nextState, ok := currentState.next[ev]; !ok {
-		panic("can not accept the event")
-	} else {
-		// exit current state
-		if currentState.exitFrom != nil && currentState.exitFrom[ev] != nil {
-			currentState.exitFrom[ev].Do()
-		} else if currentState.exitAction != nil {
-			currentState.exitAction.Do()
+		panic(fmt.Sprintf("no such state %v", fsm.StateName(fsm.currentState)))
+	} else if sub := currentState.sub; sub != nil && sub.canAccept(ev) {
+		out, err := sub.forward(ev)
+		if err == nil && sub.isDone() {
+			return fsm.transit(currentState, sub.done, ev)
 		}
+		return out, err
+	} else if t, ok := fsm.nextState(currentState, ev); !ok {
+		panic(fmt.Sprintf("state %v can not accept the event %v",
+			fsm.StateName(currentState.id), fsm.EventName(ev)))
+	} else {
+		return fsm.transit(currentState, t, ev)
+	}
+}
+
+// the Moore output of the current state
+func (fsm *stateMachine) Output() any {
+	if cs, ok := fsm.states[fsm.currentState]; ok {
+		return cs.output
+	}
+	return nil
+}
+
+// whether cs or its running sub-machine can accept ev
+func (fsm *stateMachine) canAccept(cs *interState, ev Event) bool {
+	if cs.sub != nil && cs.sub.canAccept(ev) {
+		return true
+	}
+	_, ok := fsm.nextState(cs, ev)
+	return ok
+}
 
-		// transit to next state
-		ps := currentState.id
-		fsm.currentState = nextState.id
-		if nextState.enterFrom != nil && nextState.enterFrom[ps] != nil {
-			nextState.enterFrom[ps].Do()
-		} else {
-			if nextState.enterAction != nil {
-				nextState.enterAction.Do()
+// exit currentState, and enter the next state by t
+func (fsm *stateMachine) transit(currentState *interState, t *transition, ev Event) (any, error) {
+	if t.internal {
+		if t.errAction != nil {
+			if err := fsm.do(t.errAction); err != nil {
+				return nil, fsm.transitionError(currentState.id, ev, currentState.id, err)
 			}
 		}
+		return currentState.output, nil
 	}
+
+	// exit current state
+	to := fsm.target(t).id
+	exit := currentState.exitHooks(ev, to)
+	if err := fsm.runHooks(&exit); err != nil {
+		exit.undo()
+		return nil, fsm.transitionError(currentState.id, ev, to, err)
+	}
+
+	mark := fsm.markStack()
+	nextState := fsm.applyStack(t)
+	var err error
+	if t.action != nil {
+		err = fsm.doEvent(t.action, ev)
+	}
+	if err == nil && t.errAction != nil {
+		err = fsm.do(t.errAction)
+	}
+	if err != nil {
+		fsm.rollback(mark, exit, nil, hookRun{})
+		return nil, fsm.transitionError(currentState.id, ev, nextState.id, err)
+	}
+
+	// transit to next state
+	ps := currentState.id
+	fsm.currentState = nextState.id
+	var enterErr error
+	enter := nextState.enterHooks(ps, ev)
+	if err := fsm.runHooks(&enter); err != nil {
+		enterErr = fsm.transitionError(ps, ev, nextState.id, err)
+		switch nextState.failPolicy {
+		case FailRevert:
+			fsm.rollback(mark, exit, t.errAction, enter)
+			fsm.currentState = ps
+			return nil, enterErr
+		case FailGoto:
+			return fsm.enterErrState(nextState, nextState.errState, ev, enterErr)
+		}
+	}
+
+	// start the sub-machine, which may complete at once
+	if nextState.sub != nil && fsm.currentState == nextState.id {
+		nextState.sub.enter()
+		if enterErr == nil {
+			enterErr = nextState.sub.err()
+		}
+		if enterErr == nil && nextState.sub.isDone() {
+			return fsm.transit(nextState, nextState.sub.done, ev)
+		}
+	}
+
+	if t.out != nil {
+		return t.out, enterErr
+	}
+	return nextState.output, enterErr
 }
 
+// run the fed events until none is fed, or the machine is paused or
+// stopped. the machine is stopped if a step fails, see Err.
 func (fsm *stateMachine) autoRun() {
 	for fsm.isSetNextEv() {
 		fsm.flag &= ^fsm_flag_nextev
-		fsm.Step(fsm.nextEvent)
+		if _, err := fsm.TryStep(fsm.nextEvent); err != nil {
+			fsm.Stop()
+			break
+		}
 		if fsm.isPaused() || fsm.isStopped() {
 			break
 		}
 	}
 }
 
+// whether the current state is a final state
+func (fsm *stateMachine) IsAccepting() bool {
+	cs, ok := fsm.states[fsm.currentState]
+	return ok && cs.final
+}
+
+// whether the machine is in a final state with no event to run
+func (fsm *stateMachine) Done() bool {
+	return fsm.IsAccepting() && !fsm.isSetNextEv()
+}
+
 // auto run fsm
 func (fsm *stateMachine) Start(startEv Event) {
 
@@ -253,7 +701,13 @@ func (fsm *stateMachine) Close() {
 	for _, v := range fsm.states {
 		v.enterFrom = nil
 		v.next = nil
+		v.pdNext = nil
+		v.otherwise = nil
+		v.sub = nil
 	}
 	fsm.states = nil
+	fsm.anyNext = nil
+	fsm.dense = nil
+	fsm.stack = nil
 	fsm.currentState = 0
 }
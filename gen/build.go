@@ -0,0 +1,91 @@
+package gen
+
+import (
+	"fmt"
+
+	"github.com/shory152/fsm"
+)
+
+// the interpreted machine of d, which behaves as the code generated. the
+// states and events are numbered in the order they are defined from 0,
+// and named by the machine. actions are looked up in actions by name.
+func Build(d *Definition, actions map[string]func()) (fsm.StepFSM, error) {
+	if err := d.check(); err != nil {
+		return nil, err
+	}
+	states := make(map[string]fsm.State)
+	for i, sd := range d.States {
+		states[sd.Name] = fsm.State(i)
+	}
+	events := make(map[string]fsm.Event)
+	for i, e := range d.Events {
+		events[e] = fsm.Event(i)
+	}
+	var err error
+	action := func(name string) func() {
+		if name == "" {
+			return nil
+		}
+		f, ok := actions[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("gen: action %s is not given", name)
+		}
+		return f
+	}
+
+	sm := fsm.NewStepFSM(states[d.Start])
+	for s, sd := range d.States {
+		sm.NameState(fsm.State(s), sd.Name)
+	}
+	for e, name := range d.Events {
+		sm.NameEvent(fsm.Event(e), name)
+	}
+	for _, sd := range d.States {
+		cs := sm.ConfigState(states[sd.Name])
+		if sd.Final {
+			cs.Final()
+		}
+		if f := action(sd.Enter); f != nil {
+			cs.OnEnter(fsm.ActionFunc(f))
+		}
+		if f := action(sd.Exit); f != nil {
+			cs.OnExit(fsm.ActionFunc(f))
+		}
+		for _, t := range sd.Accept {
+			f := action(t.Action)
+			switch {
+			case t.Internal:
+				cs.AcceptInternal(events[t.Event], fsm.ActionFunc(func() {
+					if f != nil {
+						f()
+					}
+				}))
+			case f != nil:
+				cs.AcceptAction(events[t.Event], states[t.To], fsm.ErrActionFunc(func() error {
+					f()
+					return nil
+				}))
+			default:
+				cs.Accept(events[t.Event], states[t.To])
+			}
+		}
+		if t := sd.Otherwise; t != nil {
+			cs.Otherwise(states[t.To])
+			if f := action(t.Action); f != nil {
+				cs.OnOtherwise(fsm.EventActionFunc(func(fsm.Event) { f() }))
+			}
+		}
+	}
+	for _, at := range d.Any {
+		var except []fsm.State
+		for _, s := range at.Except {
+			except = append(except, states[s])
+		}
+		sm.AcceptAnyExcept(events[at.Event], states[at.To], except...)
+	}
+	if err != nil {
+		sm.Close()
+		return nil, err
+	}
+	return sm, nil
+}
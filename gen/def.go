@@ -0,0 +1,278 @@
+// generate Go source of a state machine, with a switch per state and
+// neither maps nor interface calls, see cmd/fsmgen.
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"strconv"
+
+	"github.com/shory152/fsm"
+)
+
+// a machine definition, which Load reads from JSON:
+//
+//	{
+//		"package": "door",
+//		"name": "Door",
+//		"start": "Closed",
+//		"states": [
+//			{"name": "Closed", "accept": [{"event": "Open", "to": "Opened", "action": "creak"}]},
+//			{"name": "Opened", "enter": "light", "accept": [{"event": "Close", "to": "Closed"}]}
+//		]
+//	}
+//
+// actions are the names of functions func() of the package, which the
+// generated code calls, other than m, ev and fmt which it uses.
+type Definition struct {
+	Package string     `json:"package,omitempty"` // required by Generate
+	Name    string     `json:"name"`
+	Start   string     `json:"start"`
+	Events  []string   `json:"events,omitempty"` // events not listed are added in order
+	States  []StateDef `json:"states"`
+	Any     []AnyDef   `json:"any,omitempty"`
+}
+
+// a state, see ConfigState
+type StateDef struct {
+	Name      string          `json:"name"`
+	Final     bool            `json:"final,omitempty"`
+	Enter     string          `json:"enter,omitempty"`
+	Exit      string          `json:"exit,omitempty"`
+	Accept    []TransitionDef `json:"accept,omitempty"`
+	Otherwise *TransitionDef  `json:"otherwise,omitempty"` // Event is not used
+}
+
+// a transition on Event to To, executing Action after exiting the state.
+// an internal transition stays in the state without exiting it, To is
+// not used.
+type TransitionDef struct {
+	Event    string `json:"event,omitempty"`
+	To       string `json:"to,omitempty"`
+	Action   string `json:"action,omitempty"`
+	Internal bool   `json:"internal,omitempty"`
+}
+
+// a transition of the states but the except ones, see AcceptAnyExcept
+type AnyDef struct {
+	Event  string   `json:"event"`
+	To     string   `json:"to"`
+	Except []string `json:"except,omitempty"`
+}
+
+// read a definition from JSON and check it
+func Load(r io.Reader) (*Definition, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	d := &Definition{}
+	if err := dec.Decode(d); err != nil {
+		return nil, fmt.Errorf("gen: %v", err)
+	}
+	if err := d.check(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// the definition of the states and transitions of m, in the package pkg
+// and named name. states and events are named by m, or S and E followed
+// by the number. they are numbered from 0 in the order defined by Build
+// and Generate, so the values of the generated constants differ from
+// the ones of m, map them by the names. actions are not defined, and it
+// fails if m has pushdown transitions or sub-machines.
+func Describe(m interface {
+	fsm.Inspector
+	fsm.Namer
+}, pkg, name string) (*Definition, error) {
+	stateName := func(s fsm.State) string {
+		return ident(m.StateName(s), "S", int(s))
+	}
+	d := &Definition{Package: pkg, Name: name, Start: stateName(m.StartState())}
+	final := make(map[fsm.State]bool)
+	for _, s := range m.Finals() {
+		final[s] = true
+	}
+	events := make(map[fsm.Event]bool)
+	for _, s := range m.States() {
+		if m.HasSubmachine(s) {
+			return nil, fmt.Errorf("gen: state %s has a sub-machine", m.StateName(s))
+		}
+		sd := StateDef{Name: stateName(s), Final: final[s]}
+		for _, t := range m.Transitions(s) {
+			if t.Op != fsm.StackNone {
+				return nil, fmt.Errorf("gen: state %s has a pushdown transition on %s",
+					m.StateName(s), m.EventName(t.Event))
+			}
+			ev := ident(m.EventName(t.Event), "E", int(t.Event))
+			if !events[t.Event] {
+				events[t.Event] = true
+				d.Events = append(d.Events, ev)
+			}
+			if t.Internal {
+				sd.Accept = append(sd.Accept, TransitionDef{Event: ev, Internal: true})
+			} else {
+				sd.Accept = append(sd.Accept, TransitionDef{Event: ev, To: stateName(t.To)})
+			}
+		}
+		if next, ok := m.Otherwise(s); ok {
+			sd.Otherwise = &TransitionDef{To: stateName(next)}
+		}
+		d.States = append(d.States, sd)
+	}
+	return d, nil
+}
+
+// name if it is an identifier, else prefix followed by n
+func ident(name, prefix string, n int) string {
+	if token.IsIdentifier(name) {
+		return name
+	}
+	if n < 0 {
+		return prefix + "_" + strconv.Itoa(-n)
+	}
+	return prefix + strconv.Itoa(n)
+}
+
+// the receiver, the parameter and the package the generated code uses
+var reserved = map[string]bool{"m": true, "ev": true, "fmt": true}
+
+// check the names and references, and add the events not listed
+func (d *Definition) check() error {
+	if !token.IsIdentifier(d.Name) {
+		return fmt.Errorf("gen: bad machine name %q", d.Name)
+	}
+
+	states := make(map[string]bool)
+	for _, sd := range d.States {
+		if !token.IsIdentifier(sd.Name) {
+			return fmt.Errorf("gen: bad state name %q", sd.Name)
+		}
+		if states[sd.Name] {
+			return fmt.Errorf("gen: state %s is defined twice", sd.Name)
+		}
+		states[sd.Name] = true
+	}
+	state := func(s string) error {
+		if !states[s] {
+			return fmt.Errorf("gen: state %q is not defined", s)
+		}
+		return nil
+	}
+
+	events := make(map[string]bool)
+	event := func(e string) error {
+		if !token.IsIdentifier(e) {
+			return fmt.Errorf("gen: bad event name %q", e)
+		}
+		if !events[e] {
+			events[e] = true
+			d.Events = append(d.Events, e)
+		}
+		return nil
+	}
+	listed := d.Events
+	d.Events = nil
+	for _, e := range listed {
+		if events[e] {
+			return fmt.Errorf("gen: event %s is listed twice", e)
+		}
+		if err := event(e); err != nil {
+			return err
+		}
+	}
+
+	action := func(a string) error {
+		if a != "" && !token.IsIdentifier(a) {
+			return fmt.Errorf("gen: bad action name %q", a)
+		}
+		if reserved[a] {
+			return fmt.Errorf("gen: action name %q is used by the generated code", a)
+		}
+		return nil
+	}
+
+	if err := state(d.Start); err != nil {
+		return err
+	}
+	for _, sd := range d.States {
+		if err := action(sd.Enter); err != nil {
+			return err
+		}
+		if err := action(sd.Exit); err != nil {
+			return err
+		}
+		accepted := make(map[string]bool)
+		for _, t := range sd.Accept {
+			if err := event(t.Event); err != nil {
+				return err
+			}
+			if accepted[t.Event] {
+				return fmt.Errorf("gen: state %s accepts %s twice", sd.Name, t.Event)
+			}
+			accepted[t.Event] = true
+			if !t.Internal {
+				if err := state(t.To); err != nil {
+					return err
+				}
+			}
+			if err := action(t.Action); err != nil {
+				return err
+			}
+		}
+		if t := sd.Otherwise; t != nil {
+			if err := state(t.To); err != nil {
+				return err
+			}
+			if err := action(t.Action); err != nil {
+				return err
+			}
+		}
+	}
+	anyEvents := make(map[string]bool)
+	for _, at := range d.Any {
+		if err := event(at.Event); err != nil {
+			return err
+		}
+		if anyEvents[at.Event] {
+			return fmt.Errorf("gen: any state accepts %s twice", at.Event)
+		}
+		anyEvents[at.Event] = true
+		if err := state(at.To); err != nil {
+			return err
+		}
+		for _, s := range at.Except {
+			if err := state(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// the transition of sd on the event e, nil if it has none. transitions
+// of sd take precedence over the wildcard ones, see AcceptAnyExcept.
+func (d *Definition) transition(sd *StateDef, e string) *TransitionDef {
+	for i := range sd.Accept {
+		if sd.Accept[i].Event == e {
+			return &sd.Accept[i]
+		}
+	}
+	for _, at := range d.Any {
+		if at.Event != e || contains(at.Except, sd.Name) {
+			continue
+		}
+		return &TransitionDef{Event: e, To: at.To}
+	}
+	return nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
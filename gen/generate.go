@@ -0,0 +1,252 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+)
+
+// the Go source of d, formatted. for the machine Door, it declares
+//
+//	type DoorState int // constants DoorClosed, ... in the order defined
+//	type DoorEvent int // constants DoorOpen, ...
+//	type Door struct{ ... }
+//
+//	func NewDoor() *Door
+//	func (m *Door) Current() DoorState
+//	func (m *Door) Reset()
+//	func (m *Door) IsAccepting() bool
+//	func (m *Door) CanAccept(ev DoorEvent) bool
+//	func (m *Door) Step(ev DoorEvent)
+//
+// which behave as the machine built by Build.
+func Generate(d *Definition) ([]byte, error) {
+	if err := d.check(); err != nil {
+		return nil, err
+	}
+	if !token.IsIdentifier(d.Package) {
+		return nil, fmt.Errorf("gen: bad package name %q", d.Package)
+	}
+	g := &generator{d: d, idents: make(map[string]string)}
+	if err := g.declare(); err != nil {
+		return nil, err
+	}
+	g.file()
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gen: %v", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	d      *Definition
+	buf    bytes.Buffer
+	idents map[string]string // declared identifiers to what they are
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) state(s string) string {
+	return g.d.Name + s
+}
+
+func (g *generator) event(e string) string {
+	return g.d.Name + e
+}
+
+// reserve the identifiers to declare, which must not collide
+func (g *generator) declare() error {
+	d := g.d
+	reserve := func(id, what string) error {
+		if prev, ok := g.idents[id]; ok {
+			return fmt.Errorf("gen: %s and %s are both declared as %s", prev, what, id)
+		}
+		g.idents[id] = what
+		return nil
+	}
+	for _, id := range []string{d.Name, d.Name + "State", d.Name + "Event", "New" + d.Name} {
+		if err := reserve(id, "machine "+d.Name); err != nil {
+			return err
+		}
+	}
+	for _, sd := range d.States {
+		if err := reserve(g.state(sd.Name), "state "+sd.Name); err != nil {
+			return err
+		}
+	}
+	for _, e := range d.Events {
+		if err := reserve(g.event(e), "event "+e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) file() {
+	d := g.d
+	g.printf("// Code generated by fsmgen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", d.Package)
+	g.printf("import \"fmt\"\n\n")
+
+	names := make([]string, len(d.States))
+	for i, sd := range d.States {
+		names[i] = sd.Name
+	}
+	g.enum(d.Name+"State", "states", names, g.state)
+	g.enum(d.Name+"Event", "events", d.Events, g.event)
+
+	g.printf("// state machine generated from the definition %s\n", d.Name)
+	g.printf("type %s struct {\n\tstate %sState\n}\n\n", d.Name, d.Name)
+	g.printf("// a %s in the start state\n", d.Name)
+	g.printf("func New%s() *%s {\n\treturn &%s{state: %s}\n}\n\n", d.Name, d.Name, d.Name, g.state(d.Start))
+	g.printf("// the current state\n")
+	g.printf("func (m *%s) Current() %sState {\n\treturn m.state\n}\n\n", d.Name, d.Name)
+	g.printf("// return to the start state, executing no action\n")
+	g.printf("func (m *%s) Reset() {\n\tm.state = %s\n}\n\n", d.Name, g.state(d.Start))
+	g.accepting()
+	g.canAccept()
+	g.step()
+}
+
+// the type named typ, its constants, and String
+func (g *generator) enum(typ, what string, names []string, ident func(string) string) {
+	g.printf("// %s of %s\n", what, g.d.Name)
+	g.printf("type %s int\n\n", typ)
+	if len(names) > 0 {
+		g.printf("const (\n")
+		for i, name := range names {
+			if i == 0 {
+				g.printf("\t%s %s = iota\n", ident(name), typ)
+			} else {
+				g.printf("\t%s\n", ident(name))
+			}
+		}
+		g.printf(")\n\n")
+	}
+	g.printf("func (v %s) String() string {\n", typ)
+	if len(names) > 0 {
+		g.printf("\tswitch v {\n")
+		for _, name := range names {
+			g.printf("\tcase %s:\n\t\treturn %q\n", ident(name), name)
+		}
+		g.printf("\t}\n")
+	}
+	g.printf("\treturn fmt.Sprintf(\"%s(%%d)\", int(v))\n}\n\n", typ)
+}
+
+func (g *generator) accepting() {
+	d := g.d
+	var finals []string
+	for _, sd := range d.States {
+		if sd.Final {
+			finals = append(finals, g.state(sd.Name))
+		}
+	}
+	g.printf("// whether the current state is final\n")
+	g.printf("func (m *%s) IsAccepting() bool {\n", d.Name)
+	if len(finals) == 0 {
+		g.printf("\treturn false\n}\n\n")
+		return
+	}
+	g.printf("\tswitch m.state {\n\tcase %s:\n\t\treturn true\n\t}\n", strings.Join(finals, ", "))
+	g.printf("\treturn false\n}\n\n")
+}
+
+// the events sd accepts, with the transitions on them
+func (g *generator) transitions(sd *StateDef) ([]string, []*TransitionDef) {
+	var events []string
+	var ts []*TransitionDef
+	for _, e := range g.d.Events {
+		if t := g.d.transition(sd, e); t != nil {
+			events = append(events, e)
+			ts = append(ts, t)
+		}
+	}
+	return events, ts
+}
+
+func (g *generator) canAccept() {
+	d := g.d
+	g.printf("// whether ev can be accepted in the current state, true for any\n")
+	g.printf("// event if the state has an otherwise transition\n")
+	g.printf("func (m *%s) CanAccept(ev %sEvent) bool {\n", d.Name, d.Name)
+	g.printf("\tswitch m.state {\n")
+	for i := range d.States {
+		sd := &d.States[i]
+		events, _ := g.transitions(sd)
+		if len(events) == 0 && sd.Otherwise == nil {
+			continue
+		}
+		g.printf("\tcase %s:\n", g.state(sd.Name))
+		if sd.Otherwise != nil {
+			g.printf("\t\treturn true\n")
+			continue
+		}
+		for j, e := range events {
+			events[j] = g.event(e)
+		}
+		g.printf("\t\tswitch ev {\n\t\tcase %s:\n\t\t\treturn true\n\t\t}\n", strings.Join(events, ", "))
+	}
+	g.printf("\t}\n\treturn false\n}\n\n")
+}
+
+func (g *generator) step() {
+	d := g.d
+	g.printf("// feed ev, transfer to the next state executing the exit action of\n")
+	g.printf("// the current state, the transition action and the enter action of\n")
+	g.printf("// the next state. it panics if the current state can not accept ev.\n")
+	g.printf("func (m *%s) Step(ev %sEvent) {\n", d.Name, d.Name)
+	g.printf("\tswitch m.state {\n")
+	for i := range d.States {
+		sd := &d.States[i]
+		events, ts := g.transitions(sd)
+		if len(events) == 0 && sd.Otherwise == nil {
+			continue
+		}
+		g.printf("\tcase %s:\n", g.state(sd.Name))
+		if len(events) == 0 {
+			g.transit(sd, sd.Otherwise)
+			continue
+		}
+		g.printf("\t\tswitch ev {\n")
+		for j, e := range events {
+			g.printf("\t\tcase %s:\n", g.event(e))
+			g.transit(sd, ts[j])
+		}
+		if sd.Otherwise != nil {
+			g.printf("\t\tdefault:\n")
+			g.transit(sd, sd.Otherwise)
+		}
+		g.printf("\t\t}\n")
+	}
+	g.printf("\t}\n")
+	g.printf("\tpanic(fmt.Sprintf(\"state %%v can not accept the event %%v\", m.state, ev))\n}\n")
+}
+
+// the body of a case of Step
+func (g *generator) transit(sd *StateDef, t *TransitionDef) {
+	call := func(a string) {
+		if a != "" {
+			g.printf("\t\t\t%s()\n", a)
+		}
+	}
+	if t.Internal {
+		call(t.Action)
+		g.printf("\t\t\treturn\n")
+		return
+	}
+	call(sd.Exit)
+	call(t.Action)
+	g.printf("\t\t\tm.state = %s\n", g.state(t.To))
+	for _, next := range g.d.States {
+		if next.Name == t.To {
+			call(next.Enter)
+		}
+	}
+	g.printf("\t\t\treturn\n")
+}
@@ -0,0 +1,130 @@
+package fsm
+
+import (
+	"io"
+	"iter"
+)
+
+// AutoFSM whose actions emit values to the caller. the machine pauses
+// after a step emitting values, and resumes when they are all pulled.
+type Generator[T any] struct {
+	fsm     *stateMachine
+	startEv Event
+	started bool
+	done    bool
+	closed  bool
+	values  []T
+	head    int
+	err     error
+}
+
+// the machine is started by startEv when the first value is pulled
+func NewGenerator[T any](startState State, startEv Event) *Generator[T] {
+	g := &Generator[T]{}
+	g.fsm = newStateMachine(startState)
+	g.fsm.flag |= fsm_flag_auto
+	g.startEv = startEv
+	return g
+}
+
+func (g *Generator[T]) ConfigState(s State) ConfigState {
+	return g.fsm.ConfigState(s)
+}
+
+func (g *Generator[T]) AcceptAny(e Event, next State) {
+	g.fsm.AcceptAny(e, next)
+}
+
+func (g *Generator[T]) AcceptAnyExcept(e Event, next State, except ...State) {
+	g.fsm.AcceptAnyExcept(e, next, except...)
+}
+
+// feed event to fsm for auto run next step
+func (g *Generator[T]) Feed(e Event) {
+	g.fsm.Feed(e)
+}
+
+// stop the machine, values emitted are still pulled
+func (g *Generator[T]) Stop() {
+	g.fsm.Stop()
+}
+
+// emit v to the caller, the machine pauses after the current step
+func (g *Generator[T]) Emit(v T) {
+	g.values = append(g.values, v)
+	g.fsm.flag &= ^fsm_flag_running
+	g.fsm.flag |= fsm_flag_pause
+}
+
+// stop the machine with err, which is returned after the values emitted
+func (g *Generator[T]) Fail(err error) {
+	g.err = err
+	g.fsm.Stop()
+}
+
+// pull the next emitted value. it returns io.EOF when the machine is
+// stopped or no more event is fed, or the error the machine failed with,
+// by Fail or a failed action.
+func (g *Generator[T]) Next() (T, error) {
+	var zero T
+	for g.head == len(g.values) {
+		g.values = g.values[:0]
+		g.head = 0
+
+		if g.done || g.fsm.isStopped() {
+			if g.err == nil {
+				g.err = g.fsm.Err()
+			}
+			g.finish()
+			if g.err != nil {
+				return zero, g.err
+			}
+			return zero, io.EOF
+		}
+		if !g.started {
+			g.started = true
+			g.fsm.Start(g.startEv)
+		} else if g.fsm.isPaused() {
+			g.fsm.Resume()
+		} else {
+			g.done = true
+		}
+	}
+
+	v := g.values[g.head]
+	g.values[g.head] = zero
+	g.head++
+	return v, nil
+}
+
+// iterate all emitted values, the machine is closed when the
+// iteration ends.
+func (g *Generator[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer g.Close()
+		for {
+			v, err := g.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (g *Generator[T]) finish() {
+	g.done = true
+	if !g.closed {
+		g.closed = true
+		g.fsm.Close()
+	}
+}
+
+// stop and close the machine, values not pulled are dropped
+func (g *Generator[T]) Close() {
+	g.finish()
+	g.values = nil
+	g.head = 0
+}
@@ -0,0 +1,226 @@
+package fsm
+
+// how the specific actions of a hook, set by OnEnterFrom or OnExitEvent
+// and the like, run with the generic ones. only the specific actions of
+// the highest precedence run:
+//
+//	enter: OnEnterFrom(prev), OnEnterVia(event)
+//	exit:  OnExitEvent(event), OnExitTo(next)
+type HookPolicy int
+
+const (
+	// specific actions run instead of the generic ones, the default
+	SpecificInstead HookPolicy = iota
+	// specific actions run before the generic ones
+	SpecificBefore
+	// specific actions run after the generic ones
+	SpecificAfter
+)
+
+// an action of a hook
+type hook struct {
+	name string
+	prio int
+	act  ErrAction
+	// set by OnEnter and the like, which replace the previous one
+	dflt bool
+}
+
+// actions of a hook ordered by priority, higher first. actions of the
+// same priority run in the order they are added.
+type hooks []hook
+
+func (hs hooks) add(h hook) hooks {
+	if h.name != "" {
+		hs = hs.remove(h.name)
+	}
+	i := len(hs)
+	for i > 0 && hs[i-1].prio < h.prio {
+		i--
+	}
+	hs = append(hs, hook{})
+	copy(hs[i+1:], hs[i:])
+	hs[i] = h
+	return hs
+}
+
+func (hs hooks) remove(name string) hooks {
+	n := 0
+	for _, h := range hs {
+		if h.name != name {
+			hs[n] = h
+			n++
+		}
+	}
+	clear(hs[n:])
+	return hs[:n]
+}
+
+// replace the action set by OnEnter and the like
+func (hs hooks) setDefault(act ErrAction) hooks {
+	n := 0
+	for _, h := range hs {
+		if !h.dflt {
+			hs[n] = h
+			n++
+		}
+	}
+	clear(hs[n:])
+	hs = hs[:n]
+	if act == nil {
+		return hs
+	}
+	return hs.add(hook{act: act, dflt: true})
+}
+
+// undo the actions in the reverse order
+func (hs hooks) undo() {
+	for i := len(hs) - 1; i >= 0; i-- {
+		compensate(hs[i].act)
+	}
+}
+
+// the actions of a hook to run, specific and generic ones ordered by
+// the policy
+type hookRun struct {
+	first, second hooks
+}
+
+func (is *interState) order(specific, generic hooks) hookRun {
+	if len(specific) == 0 {
+		return hookRun{generic, nil}
+	}
+	switch is.hookPolicy {
+	case SpecificBefore:
+		return hookRun{specific, generic}
+	case SpecificAfter:
+		return hookRun{generic, specific}
+	}
+	return hookRun{specific, nil}
+}
+
+// the actions when enter this state from prev by e
+func (is *interState) enterHooks(prev State, e Event) hookRun {
+	specific := is.enterFrom[prev]
+	if len(specific) == 0 {
+		specific = is.enterVia[e]
+	}
+	return is.order(specific, is.enterAction)
+}
+
+// the actions when exit this state to next by e
+func (is *interState) exitHooks(e Event, next State) hookRun {
+	specific := is.exitFrom[e]
+	if len(specific) == 0 {
+		specific = is.exitTo[next]
+	}
+	return is.order(specific, is.exitAction)
+}
+
+// run the actions of r in order, r is cut to the ones done if one fails
+func (fsm *stateMachine) runHooks(r *hookRun) error {
+	for i, h := range r.first {
+		if err := fsm.do(h.act); err != nil {
+			r.first, r.second = r.first[:i], nil
+			return err
+		}
+	}
+	for i, h := range r.second {
+		if err := fsm.do(h.act); err != nil {
+			r.second = r.second[:i]
+			return err
+		}
+	}
+	return nil
+}
+
+// undo the actions of r in the reverse order
+func (r hookRun) undo() {
+	r.second.undo()
+	r.first.undo()
+}
+
+// set how the specific actions run with the generic ones
+func (is *interState) Specific(p HookPolicy) ConfigState {
+	is.fsm.configurable()
+	is.hookPolicy = p
+	return is
+}
+
+// add act executed when enter this state. name is optional, an action
+// of the same name is replaced. actions of higher prio run first.
+func (is *interState) AddEnter(name string, prio int, act Action) ConfigState {
+	is.fsm.configurable()
+	is.enterAction = is.enterAction.add(hook{name, prio, actionOf(act), false})
+	return is
+}
+
+// add act executed when enter this state from prev, as AddEnter
+func (is *interState) AddEnterFrom(prev State, name string, prio int, act Action) ConfigState {
+	is.fsm.configurable()
+	if is.enterFrom == nil {
+		is.enterFrom = make(map[State]hooks)
+	}
+	is.enterFrom[prev] = is.enterFrom[prev].add(hook{name, prio, actionOf(act), false})
+	return is
+}
+
+// add act executed when exit this state, as AddEnter
+func (is *interState) AddExit(name string, prio int, act Action) ConfigState {
+	is.fsm.configurable()
+	is.exitAction = is.exitAction.add(hook{name, prio, actionOf(act), false})
+	return is
+}
+
+// add act executed when exit this state by e, as AddEnter
+func (is *interState) AddExitEvent(e Event, name string, prio int, act Action) ConfigState {
+	is.fsm.configurable()
+	if is.exitFrom == nil {
+		is.exitFrom = make(map[Event]hooks)
+	}
+	is.exitFrom[e] = is.exitFrom[e].add(hook{name, prio, actionOf(act), false})
+	return is
+}
+
+// add act executed when exit this state to next, as AddEnter
+func (is *interState) AddExitTo(next State, name string, prio int, act Action) ConfigState {
+	is.fsm.configurable()
+	if is.exitTo == nil {
+		is.exitTo = make(map[State]hooks)
+	}
+	is.exitTo[next] = is.exitTo[next].add(hook{name, prio, actionOf(act), false})
+	return is
+}
+
+// add act executed when enter this state by e, as AddEnter
+func (is *interState) AddEnterVia(e Event, name string, prio int, act Action) ConfigState {
+	is.fsm.configurable()
+	if is.enterVia == nil {
+		is.enterVia = make(map[Event]hooks)
+	}
+	is.enterVia[e] = is.enterVia[e].add(hook{name, prio, actionOf(act), false})
+	return is
+}
+
+// remove the actions of name from all hooks of this state
+func (is *interState) RemoveAction(name string) ConfigState {
+	is.fsm.configurable()
+	if name == "" {
+		return is
+	}
+	is.enterAction = is.enterAction.remove(name)
+	is.exitAction = is.exitAction.remove(name)
+	for prev, hs := range is.enterFrom {
+		is.enterFrom[prev] = hs.remove(name)
+	}
+	for e, hs := range is.exitFrom {
+		is.exitFrom[e] = hs.remove(name)
+	}
+	for next, hs := range is.exitTo {
+		is.exitTo[next] = hs.remove(name)
+	}
+	for e, hs := range is.enterVia {
+		is.enterVia[e] = hs.remove(name)
+	}
+	return is
+}
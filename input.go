@@ -0,0 +1,298 @@
+package fsm
+
+import (
+	"fmt"
+	"io"
+)
+
+// position in the input
+type Position struct {
+	Offset int64 // byte offset, starting at 0
+	Line   int   // starting at 1
+	Column int   // in runes, starting at 1
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Input wraps an io.RuneScanner, tracks the position of the runes read
+// and supports looking ahead one rune.
+type Input struct {
+	in   io.RuneScanner
+	pos  Position // position of the next rune
+	prev Position // position before the last read rune
+}
+
+func NewInput(in io.RuneScanner) *Input {
+	if i, ok := in.(*Input); ok {
+		return i
+	}
+	i := &Input{}
+	i.Reset(in)
+	return i
+}
+
+// read from in, the position starts again
+func (i *Input) Reset(in io.RuneScanner) {
+	*i = Input{in: in, pos: Position{0, 1, 1}}
+}
+
+func (i *Input) ReadRune() (rune, int, error) {
+	c, size, err := i.in.ReadRune()
+	if err != nil {
+		return c, size, err
+	}
+	i.prev = i.pos
+	i.pos.Offset += int64(size)
+	if c == '\n' {
+		i.pos.Line++
+		i.pos.Column = 1
+	} else {
+		i.pos.Column++
+	}
+	return c, size, nil
+}
+
+// unread the last rune, as io.RuneScanner only one rune can be unread
+func (i *Input) UnreadRune() error {
+	if err := i.in.UnreadRune(); err != nil {
+		return err
+	}
+	i.pos = i.prev
+	return nil
+}
+
+// return the next rune without consuming it
+func (i *Input) Peek() (rune, error) {
+	c, _, err := i.ReadRune()
+	if err != nil {
+		return c, err
+	}
+	return c, i.UnreadRune()
+}
+
+// position of the next rune to read
+func (i *Input) Pos() Position {
+	return i.pos
+}
+
+// Classifier maps a rune read from input to an event
+type Classifier func(r rune) Event
+
+// fsm which pulls runes from an io.RuneScanner, and feeds the events
+// they are classified to.
+type InputFSM interface {
+	Inspector
+	Namer
+	ConfigState(s State) ConfigState
+	AcceptAny(e Event, next State)
+	AcceptAnyExcept(e Event, next State, except ...State)
+	OnEOF(e Event)
+	OnReadError(e Event)
+	Run() error
+	Advance() error
+	Rune() rune
+	Pos() Position
+	Unread() error
+	Peek() (rune, error)
+	Err() error
+	Recover()
+	RecoverTo(errState State)
+	Freeze()
+	Reconfigure(config func(b Builder)) error
+	Reset()
+	ResetTo(s State)
+	ResetInput(in io.RuneScanner)
+	Compile()
+	Top() (Symbol, bool)
+	Depth() int
+	IsAccepting() bool
+	Done() bool
+	Stop()
+	Close()
+}
+
+// the input part shared by Lexer and InputFSM
+type runner struct {
+	*stateMachine
+	in       *Input
+	own      *Input // created for an io.RuneScanner which is not an Input
+	r        rune
+	pos      Position
+	eofEvent Event
+	errEvent Event
+	hasEOF   bool
+	hasErrEv bool
+	done     bool
+	err      error
+}
+
+// feed e at the end of input
+func (rn *runner) OnEOF(e Event) {
+	rn.eofEvent = e
+	rn.hasEOF = true
+}
+
+// feed e when reading input fails, the error is reported by Err
+func (rn *runner) OnReadError(e Event) {
+	rn.errEvent = e
+	rn.hasErrEv = true
+}
+
+// the rune which triggers the current transition
+func (rn *runner) Rune() rune {
+	return rn.r
+}
+
+// position of the rune which triggers the current transition
+func (rn *runner) Pos() Position {
+	return rn.pos
+}
+
+// push the current rune back, it is read again by the next step
+func (rn *runner) Unread() error {
+	return rn.in.UnreadRune()
+}
+
+// return the next rune without consuming it
+func (rn *runner) Peek() (rune, error) {
+	return rn.in.Peek()
+}
+
+// as stateMachine.Reset, the input is read on from where it stops
+func (rn *runner) Reset() {
+	rn.ResetTo(rn.startState)
+}
+
+// as stateMachine.ResetTo, the input is read on from where it stops
+func (rn *runner) ResetTo(s State) {
+	rn.stateMachine.ResetTo(s)
+	rn.r = 0
+	rn.pos = Position{}
+	rn.done = false
+	rn.err = nil
+}
+
+// reset the machine, and read from in
+func (rn *runner) ResetInput(in io.RuneScanner) {
+	rn.Reset()
+	rn.bind(in)
+}
+
+// read from in, reuse the Input wrapping the previous one
+func (rn *runner) bind(in io.RuneScanner) {
+	if i, ok := in.(*Input); ok {
+		rn.in = i
+		return
+	}
+	if rn.own == nil {
+		rn.own = &Input{}
+	}
+	rn.own.Reset(in)
+	rn.in = rn.own
+}
+
+// the error reading input, or the error of the last step
+func (rn *runner) Err() error {
+	if rn.err != nil {
+		return rn.err
+	}
+	return rn.stateMachine.Err()
+}
+
+// read a rune, classify it and step. it returns io.EOF when the input
+// is exhausted or the machine is stopped, io.ErrUnexpectedEOF if the
+// current state can not accept the EOF event, or the error of a failed
+// step, which stops the machine.
+func (rn *runner) advance(classify Classifier) error {
+	if rn.done || rn.isStopped() {
+		return io.EOF
+	}
+
+	var ev Event
+	pos := rn.in.Pos()
+	c, _, err := rn.in.ReadRune()
+	switch {
+	case err == nil:
+		rn.r = c
+		rn.pos = pos
+		ev = classify(c)
+	case err == io.EOF:
+		rn.done = true
+		if !rn.hasEOF {
+			return io.EOF
+		}
+		rn.pos = pos
+		ev = rn.eofEvent
+	default:
+		rn.done = true
+		rn.err = err
+		if !rn.hasErrEv {
+			return err
+		}
+		rn.pos = pos
+		ev = rn.errEvent
+	}
+
+	if !rn.CanAccept(ev) {
+		if _, ok := rn.states[rn.currentState]; !ok {
+			return fmt.Errorf("fsm: %v: no such state %v", pos, rn.StateName(rn.currentState))
+		} else if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+		return fmt.Errorf("fsm: %v: state %v can not accept rune %q", pos, rn.StateName(rn.currentState), c)
+	}
+
+	if _, err := rn.TryStep(ev); err != nil {
+		rn.Stop()
+		return err
+	}
+	if rn.done {
+		return io.EOF
+	}
+	return nil
+}
+
+// advance until the input is exhausted or the machine is stopped
+func (rn *runner) run(classify Classifier) error {
+	if rn.isStopped() {
+		panic("FSM has been stopped")
+	}
+	rn.flag |= fsm_flag_running
+
+	for {
+		if err := rn.advance(classify); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+type inputFSM struct {
+	runner
+	classify Classifier
+}
+
+func NewInputFSM(startState State, in io.RuneScanner, classify Classifier) InputFSM {
+	ifsm := &inputFSM{}
+	ifsm.stateMachine = newStateMachine(startState)
+	ifsm.flag |= fsm_flag_auto
+	ifsm.bind(in)
+	ifsm.classify = classify
+	return ifsm
+}
+
+// drive the machine until the input is exhausted or it is stopped
+func (ifsm *inputFSM) Run() error {
+	return ifsm.run(ifsm.classify)
+}
+
+// read one rune and step, io.EOF is returned when the input is
+// exhausted or the machine is stopped.
+func (ifsm *inputFSM) Advance() error {
+	return ifsm.advance(ifsm.classify)
+}
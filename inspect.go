@@ -0,0 +1,189 @@
+package fsm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// a configured transition, a pushdown one is taken only if the stack
+// matches Op and Sym, see AcceptPush and the like. an internal one stays
+// in From without exiting it, see AcceptInternal.
+type Transition struct {
+	From     State
+	Event    Event
+	To       State
+	Op       StackOp
+	Sym      Symbol
+	Internal bool
+}
+
+func (t Transition) String() string {
+	if t.Internal {
+		return fmt.Sprintf("{%v %v %v internal}", t.From, t.Event, t.To)
+	}
+	if t.Op == StackNone {
+		return fmt.Sprintf("{%v %v %v}", t.From, t.Event, t.To)
+	}
+	return fmt.Sprintf("{%v %v %v %v %v}", t.From, t.Event, t.To, t.Op, t.Sym)
+}
+
+// read-only introspection of a machine
+type Inspector interface {
+	Current() State
+	StartState() State
+	States() []State
+	Finals() []State
+	Transitions(s State) []Transition
+	Otherwise(s State) (State, bool)
+	HasSubmachine(s State) bool
+	AcceptedEvents() []Event
+	CanAccept(ev Event) bool
+}
+
+// the current state
+func (fsm *stateMachine) Current() State {
+	return fsm.currentState
+}
+
+// the start state
+func (fsm *stateMachine) StartState() State {
+	return fsm.startState
+}
+
+// the configured states in ascending order
+func (fsm *stateMachine) States() []State {
+	states := make([]State, 0, len(fsm.states))
+	for s := range fsm.states {
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i] < states[j]
+	})
+	return states
+}
+
+// the final states in ascending order
+func (fsm *stateMachine) Finals() []State {
+	var finals []State
+	for _, s := range fsm.States() {
+		if fsm.states[s].final {
+			finals = append(finals, s)
+		}
+	}
+	return finals
+}
+
+// the transitions of s on events, including the pushdown and wildcard
+// ones, ordered by event. the otherwise transition is not listed, see
+// Otherwise. To of AcceptReturn is s, the state returned to is popped
+// from the stack.
+func (fsm *stateMachine) Transitions(s State) []Transition {
+	is, ok := fsm.states[s]
+	if !ok {
+		return nil
+	}
+
+	var ts []Transition
+	seen := make(map[Event]bool)
+	for ev, t := range is.next {
+		ts = append(ts, Transition{From: s, Event: ev, To: t.to.id, Internal: t.internal})
+		seen[ev] = true
+	}
+	for ev, pts := range is.pdNext {
+		for _, t := range pts {
+			to := s
+			if t.op != StackReturn {
+				to = t.to.id
+			}
+			ts = append(ts, Transition{From: s, Event: ev, To: to, Op: t.op, Sym: t.sym})
+		}
+	}
+	for ev, at := range fsm.anyNext {
+		if !seen[ev] && !at.except[s] {
+			ts = append(ts, Transition{From: s, Event: ev, To: at.to.id})
+		}
+	}
+
+	sort.Slice(ts, func(i, j int) bool {
+		if ts[i].Event != ts[j].Event {
+			return ts[i].Event < ts[j].Event
+		}
+		if ts[i].To != ts[j].To {
+			return ts[i].To < ts[j].To
+		}
+		if ts[i].Op != ts[j].Op {
+			return ts[i].Op < ts[j].Op
+		}
+		return ts[i].Sym < ts[j].Sym
+	})
+	return ts
+}
+
+// the next state of the otherwise transition of s, false if it has none
+func (fsm *stateMachine) Otherwise(s State) (State, bool) {
+	if is, ok := fsm.states[s]; ok && is.otherwise != nil {
+		return is.otherwise.to.id, true
+	}
+	return 0, false
+}
+
+// whether s runs a sub-machine, see Submachine
+func (fsm *stateMachine) HasSubmachine(s State) bool {
+	is, ok := fsm.states[s]
+	return ok && is.sub != nil
+}
+
+// the events the current state, or its sub-machine, can accept now in
+// ascending order. events only accepted by the otherwise transition
+// are not listed, see CanAccept.
+func (fsm *stateMachine) AcceptedEvents() []Event {
+	fsm.applyPending()
+	cs, ok := fsm.states[fsm.currentState]
+	if !ok {
+		return nil
+	}
+
+	set := make(map[Event]bool)
+	for ev := range cs.next {
+		set[ev] = true
+	}
+	for ev, pts := range cs.pdNext {
+		for _, t := range pts {
+			if fsm.matchStack(t) {
+				set[ev] = true
+			}
+		}
+	}
+	for ev, at := range fsm.anyNext {
+		if !at.except[cs.id] {
+			set[ev] = true
+		}
+	}
+	if cs.sub != nil && !cs.sub.fsm.isStopped() {
+		for _, ev := range cs.sub.fsm.AcceptedEvents() {
+			set[ev] = true
+		}
+	}
+
+	evs := make([]Event, 0, len(set))
+	for ev := range set {
+		evs = append(evs, ev)
+	}
+	sort.Slice(evs, func(i, j int) bool {
+		return evs[i] < evs[j]
+	})
+	return evs
+}
+
+// whether ev can be accepted now, true for any event if the current
+// state has an otherwise transition.
+func (fsm *stateMachine) CanAccept(ev Event) bool {
+	fsm.applyPending()
+	if d := fsm.denseTable(); d != nil {
+		if _, t := d.lookup(fsm.currentState, ev); t != nil {
+			return true
+		}
+	}
+	cs, ok := fsm.states[fsm.currentState]
+	return ok && fsm.canAccept(cs, ev)
+}
@@ -0,0 +1,195 @@
+package fsm
+
+import (
+	"io"
+	"strings"
+	"unicode"
+)
+
+// A set of runes which a Lexer transition is declared on
+type RuneClass interface {
+	Contains(r rune) bool
+}
+
+// RuneClass func
+type RuneClassFunc func(r rune) bool
+
+func (f RuneClassFunc) Contains(r rune) bool {
+	return f(r)
+}
+
+var (
+	// any rune
+	AnyRune RuneClass = RuneClassFunc(func(rune) bool { return true })
+	// white space, as defined by unicode.IsSpace
+	Space RuneClass = RuneClassFunc(unicode.IsSpace)
+)
+
+// the single rune r
+func Rune(r rune) RuneClass {
+	return RuneClassFunc(func(c rune) bool {
+		return c == r
+	})
+}
+
+// any rune in s
+func RuneSet(s string) RuneClass {
+	return RuneClassFunc(func(c rune) bool {
+		return strings.ContainsRune(s, c)
+	})
+}
+
+// any rune in [lo, hi]
+func RuneRange(lo, hi rune) RuneClass {
+	return RuneClassFunc(func(c rune) bool {
+		return c >= lo && c <= hi
+	})
+}
+
+// any rune in one of the unicode tables, such as unicode.Letter
+func InTable(tabs ...*unicode.RangeTable) RuneClass {
+	return RuneClassFunc(func(c rune) bool {
+		return unicode.IsOneOf(tabs, c)
+	})
+}
+
+// any rune not in rc
+func Not(rc RuneClass) RuneClass {
+	return RuneClassFunc(func(c rune) bool {
+		return !rc.Contains(c)
+	})
+}
+
+// events reserved by Lexer
+const (
+	// end of input
+	EventEOF Event = -1 - iota
+	// a rune in no RuneClass of the current state,
+	// it may be taken by a wildcard or otherwise transition.
+	EventRune
+
+	// events of rune class transitions count down from here
+	lexEventBase
+)
+
+// export LexState for configure each state of Lexer. only AcceptRune and
+// AcceptEOF return LexState, the methods of ConfigState return
+// ConfigState, so AcceptRune must come first in a chain:
+//
+//	lx.ConfigState(s).AcceptRune(Space, s).OnEnter(a)
+//
+// or get the LexState again by lx.ConfigState(s).
+type LexState interface {
+	ConfigState
+	AcceptRune(rc RuneClass, next State) LexState
+	AcceptEOF(next State) LexState
+}
+
+// fsm which reads runes from an io.RuneScanner itself, and transfers
+// on the RuneClass the rune belongs to.
+type Lexer interface {
+	Inspector
+	Namer
+	ConfigState(s State) LexState
+	AcceptAny(e Event, next State)
+	AcceptAnyExcept(e Event, next State, except ...State)
+	Run(in io.RuneScanner) error
+	Rune() rune
+	Pos() Position
+	Unread() error
+	Peek() (rune, error)
+	Recover()
+	RecoverTo(errState State)
+	Freeze()
+	Reset()
+	ResetTo(s State)
+	Compile()
+	Top() (Symbol, bool)
+	Depth() int
+	IsAccepting() bool
+	Done() bool
+	Stop()
+	Close()
+}
+
+type runeTransition struct {
+	rc RuneClass
+	ev Event
+}
+
+type lexState struct {
+	*interState
+	lx    *lexer
+	runes []runeTransition
+}
+
+// this state accept a rune in rc, then transfer to next.
+// classes are tried in the order they are accepted.
+func (ls *lexState) AcceptRune(rc RuneClass, next State) LexState {
+	ls.fsm.configurable()
+	ev := ls.lx.runeEvent
+	ls.lx.runeEvent--
+	ls.runes = append(ls.runes, runeTransition{rc, ev})
+	ls.interState.Accept(ev, next)
+	return ls
+}
+
+// this state accept the end of input, then transfer to next
+func (ls *lexState) AcceptEOF(next State) LexState {
+	ls.interState.Accept(EventEOF, next)
+	return ls
+}
+
+type lexer struct {
+	runner
+	lexStates map[State]*lexState
+	runeEvent Event
+}
+
+func NewLexer(startState State) Lexer {
+	lx := &lexer{}
+	lx.stateMachine = newStateMachine(startState)
+	lx.flag |= fsm_flag_auto
+	lx.OnEOF(EventEOF)
+	lx.NameEvent(EventEOF, "EOF")
+	lx.NameEvent(EventRune, "Rune")
+	lx.lexStates = make(map[State]*lexState)
+	lx.runeEvent = lexEventBase
+	return lx
+}
+
+func (lx *lexer) ConfigState(s State) LexState {
+	if ls, ok := lx.lexStates[s]; ok {
+		return ls
+	}
+	ls := &lexState{}
+	ls.interState = lx.stateMachine.ConfigState(s).(*interState)
+	ls.lx = lx
+	lx.lexStates[s] = ls
+	return ls
+}
+
+// map c to the event of the first matched rune class of the current state
+func (lx *lexer) classify(c rune) Event {
+	if ls, ok := lx.lexStates[lx.currentState]; ok {
+		for _, rt := range ls.runes {
+			if rt.rc.Contains(c) {
+				return rt.ev
+			}
+		}
+	}
+	return EventRune
+}
+
+// read runes from in and drive the lexer, until it is stopped or
+// the end of input is accepted. it returns io.ErrUnexpectedEOF if the
+// current state can not accept the end of input.
+func (lx *lexer) Run(in io.RuneScanner) error {
+	lx.bind(in)
+	return lx.run(lx.classify)
+}
+
+func (lx *lexer) Close() {
+	lx.stateMachine.Close()
+	lx.lexStates = nil
+}
@@ -0,0 +1,107 @@
+package fsm
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// the number of s, a machine names it by Namer
+func (s State) String() string {
+	return strconv.Itoa(int(s))
+}
+
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// parse a number
+func (s *State) UnmarshalText(b []byte) error {
+	n, err := strconv.Atoi(string(b))
+	if err != nil {
+		return fmt.Errorf("fsm: bad state %q", b)
+	}
+	*s = State(n)
+	return nil
+}
+
+// the number of e, a machine names it by Namer
+func (e Event) String() string {
+	return strconv.Itoa(int(e))
+}
+
+func (e Event) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// parse a number
+func (e *Event) UnmarshalText(b []byte) error {
+	n, err := strconv.Atoi(string(b))
+	if err != nil {
+		return fmt.Errorf("fsm: bad event %q", b)
+	}
+	*e = Event(n)
+	return nil
+}
+
+// names of states and events of a machine, states and events not named
+// are shown by the number
+type Namer interface {
+	NameState(s State, name string)
+	NameEvent(e Event, name string)
+	NameStates(first State, names ...string)
+	NameEvents(first Event, names ...string)
+	StateName(s State) string
+	EventName(e Event) string
+}
+
+// Namer of a machine
+type namer struct {
+	stateNames map[State]string
+	eventNames map[Event]string
+}
+
+func (n *namer) NameState(s State, name string) {
+	if n.stateNames == nil {
+		n.stateNames = make(map[State]string)
+	}
+	n.stateNames[s] = name
+}
+
+func (n *namer) NameEvent(e Event, name string) {
+	if n.eventNames == nil {
+		n.eventNames = make(map[Event]string)
+	}
+	n.eventNames[e] = name
+}
+
+// name states from first on, suits go generate:
+//
+//	sm.NameStates(S_start, "S_start", "S_lt", "S_gt")
+func (n *namer) NameStates(first State, names ...string) {
+	for i, name := range names {
+		n.NameState(first+State(i), name)
+	}
+}
+
+// name events from first on
+func (n *namer) NameEvents(first Event, names ...string) {
+	for i, name := range names {
+		n.NameEvent(first+Event(i), name)
+	}
+}
+
+// the name of s in this machine, or its number
+func (n *namer) StateName(s State) string {
+	if name, ok := n.stateNames[s]; ok {
+		return name
+	}
+	return s.String()
+}
+
+// the name of e in this machine, or its number
+func (n *namer) EventName(e Event) string {
+	if name, ok := n.eventNames[e]; ok {
+		return name
+	}
+	return e.String()
+}
@@ -0,0 +1,272 @@
+package fsm
+
+import (
+	"sort"
+)
+
+// export NFAState for configure each state of NFA
+type NFAState interface {
+	// this state accept e, then transfer to all of next
+	Accept(e Event, next ...State) NFAState
+	// this state transfer to all of next without an event
+	Epsilon(next ...State) NFAState
+	Final() NFAState
+}
+
+// nondeterministic fsm, which may accept an event to several states and
+// move without an event. it is in the set of the states reached by the
+// events fed, with the ones reached from them by epsilon moves, and
+// accepts if any of them is final.
+type NFA interface {
+	Namer
+	ConfigState(s State) NFAState
+	StartState() State
+	States() []State
+	Finals() []State
+	Transitions(s State) []Transition
+	Epsilons(s State) []State
+	Step(ev Event) bool
+	Match(evs ...Event) bool
+	Active() []State
+	CanAccept(ev Event) bool
+	IsAccepting() bool
+	Reset()
+	Close()
+}
+
+type nfaState struct {
+	id      State
+	next    map[Event][]*nfaState
+	epsilon []*nfaState
+	final   bool
+	seen    uint64 // generation of the step reached in
+	nfa     *nfa
+}
+
+type nfa struct {
+	namer
+	startState State
+	states     map[State]*nfaState
+	active     []*nfaState
+	spare      []*nfaState
+	gen        uint64
+	started    bool
+}
+
+func NewNFA(startState State) NFA {
+	n := &nfa{}
+	n.startState = startState
+	n.states = make(map[State]*nfaState)
+	return n
+}
+
+func (n *nfa) ConfigState(s State) NFAState {
+	return n.state(s)
+}
+
+func (n *nfa) state(s State) *nfaState {
+	if ns, ok := n.states[s]; ok {
+		return ns
+	}
+	ns := &nfaState{id: s, nfa: n}
+	n.states[s] = ns
+	return ns
+}
+
+// add ns to set once
+func addState(set []*nfaState, ns *nfaState) []*nfaState {
+	for _, v := range set {
+		if v == ns {
+			return set
+		}
+	}
+	return append(set, ns)
+}
+
+func (ns *nfaState) Accept(e Event, next ...State) NFAState {
+	if ns.next == nil {
+		ns.next = make(map[Event][]*nfaState)
+	}
+	for _, s := range next {
+		ns.next[e] = addState(ns.next[e], ns.nfa.state(s))
+	}
+	return ns
+}
+
+func (ns *nfaState) Epsilon(next ...State) NFAState {
+	for _, s := range next {
+		ns.epsilon = addState(ns.epsilon, ns.nfa.state(s))
+	}
+	return ns
+}
+
+func (ns *nfaState) Final() NFAState {
+	ns.final = true
+	return ns
+}
+
+func (n *nfa) StartState() State {
+	return n.startState
+}
+
+// the configured states in ascending order
+func (n *nfa) States() []State {
+	states := make([]State, 0, len(n.states))
+	for s := range n.states {
+		states = append(states, s)
+	}
+	sortStates(states)
+	return states
+}
+
+// the final states in ascending order
+func (n *nfa) Finals() []State {
+	var finals []State
+	for _, s := range n.States() {
+		if n.states[s].final {
+			finals = append(finals, s)
+		}
+	}
+	return finals
+}
+
+// the transitions of s on events, one for each next state, ordered by
+// event and next state
+func (n *nfa) Transitions(s State) []Transition {
+	ns, ok := n.states[s]
+	if !ok {
+		return nil
+	}
+	var ts []Transition
+	for ev, next := range ns.next {
+		for _, to := range next {
+			ts = append(ts, Transition{From: s, Event: ev, To: to.id})
+		}
+	}
+	sort.Slice(ts, func(i, j int) bool {
+		if ts[i].Event != ts[j].Event {
+			return ts[i].Event < ts[j].Event
+		}
+		return ts[i].To < ts[j].To
+	})
+	return ts
+}
+
+// the states s moves to without an event in ascending order
+func (n *nfa) Epsilons(s State) []State {
+	ns, ok := n.states[s]
+	if !ok {
+		return nil
+	}
+	var states []State
+	for _, e := range ns.epsilon {
+		states = append(states, e.id)
+	}
+	sortStates(states)
+	return states
+}
+
+// add ns and the states reached from it by epsilon moves to set, the
+// ones reached in this generation are skipped
+func (n *nfa) closure(set []*nfaState, ns *nfaState) []*nfaState {
+	if ns.seen == n.gen {
+		return set
+	}
+	ns.seen = n.gen
+	set = append(set, ns)
+	for _, e := range ns.epsilon {
+		set = n.closure(set, e)
+	}
+	return set
+}
+
+// return to the start state and the states reached from it by epsilon
+// moves, the configuration is kept
+func (n *nfa) Reset() {
+	n.started = true
+	n.gen++
+	n.active = n.active[:0]
+	n.active = n.closure(n.active, n.state(n.startState))
+}
+
+func (n *nfa) start() {
+	if !n.started {
+		n.Reset()
+	}
+}
+
+// feed the Event ev to all the active states, and transfer to the
+// states they accept ev to. it returns false if none accepts ev, the
+// machine then accepts nothing until Reset.
+func (n *nfa) Step(ev Event) bool {
+	n.start()
+	n.gen++
+	next := n.spare[:0]
+	for _, ns := range n.active {
+		for _, to := range ns.next[ev] {
+			next = n.closure(next, to)
+		}
+	}
+	n.active, n.spare = next, n.active
+	return len(n.active) > 0
+}
+
+// reset, step by evs, and return whether it ends accepting
+func (n *nfa) Match(evs ...Event) bool {
+	n.Reset()
+	for _, ev := range evs {
+		if !n.Step(ev) {
+			return false
+		}
+	}
+	return n.IsAccepting()
+}
+
+// the active states in ascending order
+func (n *nfa) Active() []State {
+	n.start()
+	states := make([]State, 0, len(n.active))
+	for _, ns := range n.active {
+		states = append(states, ns.id)
+	}
+	sortStates(states)
+	return states
+}
+
+// whether any active state can accept ev
+func (n *nfa) CanAccept(ev Event) bool {
+	n.start()
+	for _, ns := range n.active {
+		if len(ns.next[ev]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// whether any active state is final
+func (n *nfa) IsAccepting() bool {
+	n.start()
+	for _, ns := range n.active {
+		if ns.final {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *nfa) Close() {
+	for _, ns := range n.states {
+		ns.next = nil
+		ns.epsilon = nil
+	}
+	n.states = nil
+	n.active = nil
+	n.spare = nil
+}
+
+func sortStates(states []State) {
+	sort.Slice(states, func(i, j int) bool {
+		return states[i] < states[j]
+	})
+}
@@ -0,0 +1,35 @@
+package fsm
+
+import (
+	"sync"
+)
+
+// Pool keeps configured machines for reuse, so that the configuration
+// is built once. it is safe for concurrent use.
+type Pool[M any] struct {
+	pool  sync.Pool
+	new   func() M
+	reset func(M)
+}
+
+// new builds a configured machine. reset, if not nil, resets a machine
+// released before it is acquired again, such as StepFSM.Reset.
+func NewPool[M any](new func() M, reset func(M)) *Pool[M] {
+	return &Pool[M]{new: new, reset: reset}
+}
+
+// a machine from the pool, which is reset, or a new one
+func (p *Pool[M]) Acquire() M {
+	if m, ok := p.pool.Get().(M); ok {
+		if p.reset != nil {
+			p.reset(m)
+		}
+		return m
+	}
+	return p.new()
+}
+
+// return m to the pool, m must not be used after
+func (p *Pool[M]) Release(m M) {
+	p.pool.Put(m)
+}
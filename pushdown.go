@@ -0,0 +1,167 @@
+package fsm
+
+// stack symbol of the pushdown transitions
+type Symbol int
+
+const (
+	// matches the empty stack in AcceptIfTop
+	EmptyStack Symbol = -1 - iota
+	// reported by Top for the frame pushed by AcceptCall
+	CallFrame
+)
+
+// the stack operation of a pushdown transition, see Transition
+type StackOp int
+
+const (
+	StackNone StackOp = iota
+	StackPush
+	StackPop
+	StackIfTop
+	StackCall
+	StackReturn
+)
+
+func (op StackOp) String() string {
+	switch op {
+	case StackPush:
+		return "push"
+	case StackPop:
+		return "pop"
+	case StackIfTop:
+		return "top"
+	case StackCall:
+		return "call"
+	case StackReturn:
+		return "return"
+	}
+	return "none"
+}
+
+type stackEntry struct {
+	sym Symbol
+	ret *interState
+}
+
+func (is *interState) acceptStack(e Event, t *transition) ConfigState {
+	if is.pdNext == nil {
+		is.pdNext = make(map[Event][]*transition)
+	}
+	is.pdNext[e] = append(is.pdNext[e], t)
+	return is
+}
+
+// this state accept e, push sym, then transfer to next.
+// pushdown transitions are tried in the order they are configured,
+// before the ones configured by Accept.
+func (is *interState) AcceptPush(e Event, sym Symbol, next State) ConfigState {
+	is.fsm.configurable()
+	nis := is.fsm.ConfigState(next).(*interState)
+	return is.acceptStack(e, &transition{to: nis, op: StackPush, sym: sym})
+}
+
+// this state accept e if sym is on the top of the stack, pop it, then
+// transfer to next
+func (is *interState) AcceptPop(e Event, sym Symbol, next State) ConfigState {
+	is.fsm.configurable()
+	nis := is.fsm.ConfigState(next).(*interState)
+	return is.acceptStack(e, &transition{to: nis, op: StackPop, sym: sym})
+}
+
+// this state accept e if sym is on the top of the stack, or the stack
+// is empty for EmptyStack, then transfer to next
+func (is *interState) AcceptIfTop(e Event, sym Symbol, next State) ConfigState {
+	is.fsm.configurable()
+	nis := is.fsm.ConfigState(next).(*interState)
+	return is.acceptStack(e, &transition{to: nis, op: StackIfTop, sym: sym})
+}
+
+// this state accept e, push ret as the return state, then transfer to
+// sub, the first state of a sub-machine.
+func (is *interState) AcceptCall(e Event, sub State, ret State) ConfigState {
+	is.fsm.configurable()
+	nis := is.fsm.ConfigState(sub).(*interState)
+	ris := is.fsm.ConfigState(ret).(*interState)
+	return is.acceptStack(e, &transition{to: nis, op: StackCall, sym: CallFrame, ret: ris})
+}
+
+// this state accept e if a call frame is on the top of the stack, pop
+// it, then transfer to its return state
+func (is *interState) AcceptReturn(e Event) ConfigState {
+	is.fsm.configurable()
+	return is.acceptStack(e, &transition{op: StackReturn, sym: CallFrame})
+}
+
+// whether the stack satisfies the condition of t
+func (fsm *stateMachine) matchStack(t *transition) bool {
+	switch t.op {
+	case StackPop, StackIfTop, StackReturn:
+		if len(fsm.stack) == 0 {
+			return t.op == StackIfTop && t.sym == EmptyStack
+		}
+		return fsm.stack[len(fsm.stack)-1].sym == t.sym
+	}
+	return true
+}
+
+// the state t transfers to
+func (fsm *stateMachine) target(t *transition) *interState {
+	if t.op == StackReturn {
+		return fsm.stack[len(fsm.stack)-1].ret
+	}
+	return t.to
+}
+
+// the stack before a transition, restored by rollback
+type stackMark struct {
+	depth int
+	top   stackEntry
+}
+
+func (fsm *stateMachine) markStack() stackMark {
+	m := stackMark{depth: len(fsm.stack)}
+	if m.depth > 0 {
+		m.top = fsm.stack[m.depth-1]
+	}
+	return m
+}
+
+// a transition pushes or pops at most one entry, so the stack is
+// restored by putting back the top
+func (fsm *stateMachine) restoreStack(m stackMark) {
+	if m.depth == 0 {
+		fsm.stack = fsm.stack[:0]
+		return
+	}
+	fsm.stack = append(fsm.stack[:m.depth-1], m.top)
+}
+
+// apply the stack operation of t, and return the state it transfers to
+func (fsm *stateMachine) applyStack(t *transition) *interState {
+	switch t.op {
+	case StackPush:
+		fsm.stack = append(fsm.stack, stackEntry{sym: t.sym})
+	case StackCall:
+		fsm.stack = append(fsm.stack, stackEntry{sym: CallFrame, ret: t.ret})
+	case StackPop:
+		fsm.stack = fsm.stack[:len(fsm.stack)-1]
+	case StackReturn:
+		ret := fsm.stack[len(fsm.stack)-1].ret
+		fsm.stack = fsm.stack[:len(fsm.stack)-1]
+		return ret
+	}
+	return t.to
+}
+
+// the symbol on the top of the stack, false if the stack is empty
+func (fsm *stateMachine) Top() (Symbol, bool) {
+	if len(fsm.stack) == 0 {
+		return EmptyStack, false
+	}
+	return fsm.stack[len(fsm.stack)-1].sym, true
+}
+
+// the depth of the stack
+func (fsm *stateMachine) Depth() int {
+	return len(fsm.stack)
+}
@@ -0,0 +1,99 @@
+package fsm
+
+import (
+	"fmt"
+)
+
+// configures a new transition table, see Reconfigure
+type Builder interface {
+	ConfigState(s State) ConfigState
+	AcceptAny(e Event, next State)
+	AcceptAnyExcept(e Event, next State, except ...State)
+}
+
+// a transition table built by Reconfigure
+type table struct {
+	states  map[State]*interState
+	anyNext map[Event]*anyTransition
+}
+
+// reject further configuration, ConfigState and the methods configuring
+// a state panic. the machine can still be reconfigured by Reconfigure.
+func (fsm *stateMachine) Freeze() {
+	fsm.flag |= fsm_flag_frozen
+}
+
+// check the machine can be configured, and drop the dense table
+func (fsm *stateMachine) configurable() {
+	if fsm.isFrozen() {
+		panic("FSM is frozen")
+	}
+	fsm.dense = nil
+}
+
+// build a new transition table from scratch by config, and swap it in
+// before the next step or lookup, such as CanAccept. it may be called by
+// an action, or by another goroutine while the machine runs, the step
+// running is not affected. it fails if the start state is not
+// configured. the next step fails and the table is kept if the state the
+// machine is in, or a state it will return to, is not configured.
+func (fsm *stateMachine) Reconfigure(config func(b Builder)) error {
+	b := newStateMachine(fsm.startState)
+	config(b)
+	if _, ok := b.states[fsm.startState]; !ok {
+		return fmt.Errorf("fsm: start state %v is not configured", fsm.StateName(fsm.startState))
+	}
+	fsm.pending.Store(&table{b.states, b.anyNext})
+	return nil
+}
+
+// swap in the table pending by Reconfigure, if any, before a lookup of
+// the table. a table failing to swap in is left pending, so that the
+// next step reports it.
+func (fsm *stateMachine) applyPending() {
+	if t := fsm.pending.Swap(nil); t != nil && fsm.swap(t) != nil {
+		fsm.pending.CompareAndSwap(nil, t)
+	}
+}
+
+// replace the transition table by t between steps
+func (fsm *stateMachine) swap(t *table) error {
+	missing := func(s State) error {
+		if _, ok := t.states[s]; !ok {
+			return fmt.Errorf("fsm: state %v is not configured by Reconfigure", fsm.StateName(s))
+		}
+		return nil
+	}
+	if err := missing(fsm.currentState); err != nil {
+		return err
+	}
+	for _, e := range fsm.stack {
+		if e.ret == nil {
+			continue
+		}
+		if err := missing(e.ret.id); err != nil {
+			return err
+		}
+	}
+	if fsm.panicState != nil {
+		if err := missing(fsm.panicState.id); err != nil {
+			return err
+		}
+	}
+
+	for _, is := range t.states {
+		is.fsm = fsm
+	}
+	for i, e := range fsm.stack {
+		if e.ret != nil {
+			fsm.stack[i].ret = t.states[e.ret.id]
+		}
+	}
+	if fsm.panicState != nil {
+		fsm.panicState = t.states[fsm.panicState.id]
+	}
+	fsm.states = t.states
+	fsm.anyNext = t.anyNext
+	fsm.dense = nil
+	return nil
+}
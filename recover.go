@@ -0,0 +1,72 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// a panic recovered in an action, it is wrapped in a TransitionError
+// telling the transition it occurs in.
+type PanicError struct {
+	Value any    // the value passed to panic
+	Stack []byte // the stack where it panics
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// the value passed to panic if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+func isPanic(err error) bool {
+	var pe *PanicError
+	return errors.As(err, &pe)
+}
+
+// recover panics in actions, a panic fails the action as an error, see
+// TryStep. the machine stays in the state where the panic occurs after
+// the rollback.
+func (fsm *stateMachine) Recover() {
+	fsm.flag |= fsm_flag_recover
+}
+
+// as Recover, but the machine enters errState after a panic, and the
+// step succeeds. the enter action of errState gets the panic by Err.
+func (fsm *stateMachine) RecoverTo(errState State) {
+	fsm.Recover()
+	fsm.panicState = fsm.ConfigState(errState).(*interState)
+}
+
+// run a, recover the panic in it if the machine recovers
+func (fsm *stateMachine) do(a ErrAction) error {
+	if !fsm.isRecovering() {
+		return a.Do()
+	}
+	return safely(a.Do)
+}
+
+// run the EventAction a with ev as do
+func (fsm *stateMachine) doEvent(a EventAction, ev Event) error {
+	if !fsm.isRecovering() {
+		a.Do(ev)
+		return nil
+	}
+	return safely(func() error {
+		a.Do(ev)
+		return nil
+	})
+}
+
+func safely(f func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{v, debug.Stack()}
+		}
+	}()
+	return f()
+}
@@ -0,0 +1,102 @@
+package fsm
+
+// a machine running in a state of its parent
+type subMachine struct {
+	fsm   *stateMachine
+	auto  bool
+	start Event
+	done  *transition
+	final map[State]bool
+}
+
+func (is *interState) submachine(child *stateMachine, done State, final []State) *subMachine {
+	sub := &subMachine{}
+	sub.fsm = child
+	sub.done = &transition{to: is.fsm.ConfigState(done).(*interState)}
+	sub.final = make(map[State]bool)
+	for _, s := range final {
+		sub.final[s] = true
+	}
+	is.sub = sub
+	return sub
+}
+
+// entering this state starts child from its start state. events are
+// forwarded to child while it can accept them, and this state transfers
+// to done when child enters one of the final states, or a state marked
+// Final in child, or is stopped.
+func (is *interState) Submachine(child StepFSM, done State, final ...State) ConfigState {
+	is.fsm.configurable()
+	c, ok := child.(*stateMachine)
+	if !ok {
+		panic("child is not created by NewStepFSM")
+	}
+	is.submachine(c, done, final)
+	return is
+}
+
+// as Submachine, but child is started by the start event and runs
+// automatically, events forwarded to it are fed. a step of child failing
+// stops it, and the step of this machine returns the error staying in
+// this state.
+func (is *interState) AutoSubmachine(child AutoFSM, start Event, done State, final ...State) ConfigState {
+	is.fsm.configurable()
+	c, ok := child.(*stateMachine)
+	if !ok {
+		panic("child is not created by NewAutoFSM")
+	}
+	sub := is.submachine(c, done, final)
+	sub.auto = true
+	sub.start = start
+	return is
+}
+
+// restart the child
+func (sub *subMachine) enter() {
+	sub.fsm.reset()
+	if sub.auto {
+		sub.fsm.Start(sub.start)
+	}
+}
+
+// whether the child can accept ev
+func (sub *subMachine) canAccept(ev Event) bool {
+	c := sub.fsm
+	if c.isStopped() {
+		return false
+	}
+	cs, ok := c.states[c.currentState]
+	return ok && c.canAccept(cs, ev)
+}
+
+// forward ev which the child can accept, return the output of the
+// step, or the output of the state it stops in for an auto child. an
+// auto child failing is stopped, and its error is returned.
+func (sub *subMachine) forward(ev Event) (any, error) {
+	c := sub.fsm
+	if sub.auto {
+		c.flag &= ^fsm_flag_pause
+		c.flag |= fsm_flag_running
+		c.Feed(ev)
+		c.autoRun()
+		return c.Output(), sub.err()
+	}
+	return c.TryStep(ev)
+}
+
+// the error which stopped an auto child
+func (sub *subMachine) err() error {
+	if sub.auto && sub.fsm.isStopped() {
+		return sub.fsm.Err()
+	}
+	return nil
+}
+
+// whether the child has completed, an auto child stopped by an error
+// has not
+func (sub *subMachine) isDone() bool {
+	if sub.err() != nil {
+		return false
+	}
+	return sub.fsm.isStopped() || sub.final[sub.fsm.currentState] || sub.fsm.IsAccepting()
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+// a machine of n states, state i accepts event j to (i+j)%n, state 0
+// also has pushdown transitions and the last one an otherwise transition
+func ring(n int) fsm.StepFSM {
+	sm := fsm.NewStepFSM(0)
+	for i := 0; i < n; i++ {
+		s := sm.ConfigState(fsm.State(i))
+		for j := 1; j < n/2; j++ {
+			s.Accept(fsm.Event(j), fsm.State((i+j)%n))
+		}
+	}
+	sm.ConfigState(0).AcceptPush(fsm.Event(n), 1, 1).AcceptPop(fsm.Event(n+1), 1, 0)
+	sm.ConfigState(fsm.State(n - 1)).Otherwise(0)
+	sm.AcceptAnyExcept(fsm.Event(n+2), 2, 0)
+	return sm
+}
+
+func TestCompile(t *testing.T) {
+	const n = 10
+	events := []fsm.Event{1, 2, n + 2, 3, n + 2, 4, 4, 2, 100, n + 2, 1, n, 3, n + 1, 7, 1, 1}
+	var want []string
+	for _, compile := range []bool{false, true} {
+		sm := ring(n)
+		if compile {
+			sm.Compile()
+		}
+		var got []string
+		for _, ev := range events {
+			if !sm.CanAccept(ev) {
+				got = append(got, "x")
+				continue
+			}
+			sm.Step(ev)
+			got = append(got, fmt.Sprint(sm.Current(), sm.Depth()))
+		}
+		if !compile {
+			want = got
+		} else if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("compiled %v, want %v", got, want)
+		}
+
+		// configuration changed after Compile
+		sm.ConfigState(sm.Current()).Accept(50, 5)
+		sm.Step(50)
+		if sm.Current() != 5 {
+			t.Errorf("compile %v: in %v after reconfigured", compile, sm.Current())
+		}
+		sm.Close()
+	}
+}
+
+func benchStep(b *testing.B, compile bool) {
+	const n = 20
+	sm := ring(n)
+	defer sm.Close()
+	if compile {
+		sm.Compile()
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sm.Step(fsm.Event(i%(n/2-1) + 1))
+	}
+}
+
+func BenchmarkStepMap(b *testing.B) {
+	benchStep(b, false)
+}
+
+func BenchmarkStepDense(b *testing.B) {
+	benchStep(b, true)
+}
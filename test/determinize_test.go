@@ -0,0 +1,163 @@
+package test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+// step a and b by random events, and check they accept the same ones
+// and end in final states together
+func equivalent(t *testing.T, a, b fsm.StepFSM, events int) {
+	t.Helper()
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		a.Reset()
+		b.Reset()
+		var evs []fsm.Event
+		for j := 0; j < 10; j++ {
+			ev := fsm.Event(rnd.Intn(events))
+			evs = append(evs, ev)
+			if a.CanAccept(ev) != b.CanAccept(ev) {
+				t.Fatalf("%v accepted %v, minimal %v", evs, a.CanAccept(ev), b.CanAccept(ev))
+			}
+			if !a.CanAccept(ev) {
+				break
+			}
+			a.Step(ev)
+			b.Step(ev)
+			if a.IsAccepting() != b.IsAccepting() {
+				t.Fatalf("%v accepting %v, minimal %v", evs, a.IsAccepting(), b.IsAccepting())
+			}
+		}
+	}
+}
+
+func TestDeterminize(t *testing.T) {
+	n := abNFA()
+	defer n.Close()
+	sm, old := fsm.Determinize(n)
+	defer sm.Close()
+
+	if got := sm.StateName(sm.StartState()); got != "{0,1,4,5}" {
+		t.Errorf("start %v", got)
+	}
+	if got := fmt.Sprint(old[S0]); got != "[0]" {
+		t.Errorf("S0 in %v", got)
+	}
+	final := make(map[fsm.State]bool)
+	for _, s := range sm.Finals() {
+		final[s] = true
+	}
+	for _, s := range old[S6] {
+		if !final[s] {
+			t.Errorf("%v of S6 is not final", sm.StateName(s))
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		evs := make([]fsm.Event, rnd.Intn(8))
+		for j := range evs {
+			evs[j] = fsm.Event(rnd.Intn(2))
+		}
+		sm.Reset()
+		if got, _ := fsm.RunEvents(sm, evs...); got != n.Match(evs...) {
+			t.Fatalf("match %q %v", abWord(evs), got)
+		}
+	}
+}
+
+// words of a and b ending with b
+//
+//	   | a  | b
+//	---+----+----
+//	S0 | S1 | S2
+//	S1 | S1 | S2
+//	S2 | S3 | S2  final
+//	S3 | S3 | S4
+//	S4 | S1 | S4  final
+func TestMinimize(t *testing.T) {
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(Ea, S1).Accept(Eb, S2)
+	sm.ConfigState(S1).Accept(Ea, S1).Accept(Eb, S2)
+	sm.ConfigState(S2).Accept(Ea, S3).Accept(Eb, S2).Final()
+	sm.ConfigState(S3).Accept(Ea, S3).Accept(Eb, S4)
+	sm.ConfigState(S4).Accept(Ea, S1).Accept(Eb, S4).Final()
+	sm.NameState(S2, "end")
+
+	m, old := fsm.Minimize(sm)
+	defer m.Close()
+	if got := fmt.Sprint(m.States()); got != "[0 1]" {
+		t.Fatalf("states %v", got)
+	}
+	if got := fmt.Sprint(old); got != "map[0:[0] 1:[0] 2:[1] 3:[0] 4:[1]]" {
+		t.Errorf("mapping %v", got)
+	}
+	if got := m.StateName(1); got != "{end,4}" {
+		t.Errorf("state 1 named %v", got)
+	}
+	equivalent(t, sm, m, 2)
+}
+
+func TestMinimizeOtherwise(t *testing.T) {
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(E1, S1).Accept(E2, S2).Accept(E3, S3).Accept(E4, S4)
+	sm.ConfigState(S1).Otherwise(S0)
+	sm.ConfigState(S2).Otherwise(S0)
+	sm.ConfigState(S3)
+	sm.ConfigState(S4).Accept(E1, S4)
+	sm.ConfigState(S5).Final()
+
+	m, old := fsm.Minimize(sm)
+	defer m.Close()
+	if got := fmt.Sprint(old); got != "map[0:[0] 1:[1] 2:[1] 3:[2] 4:[3]]" {
+		t.Errorf("mapping %v", got)
+	}
+	if m.Step(E1); !m.CanAccept(E5) {
+		t.Errorf("otherwise is lost")
+	}
+	equivalent(t, sm, m, 6)
+
+	again, _ := fsm.Minimize(m)
+	defer again.Close()
+	if len(again.States()) != len(m.States()) {
+		t.Errorf("minimized to %v, again %v", m.States(), again.States())
+	}
+}
+
+func TestMinimizeNFA(t *testing.T) {
+	n := abNFA()
+	defer n.Close()
+	d, _ := fsm.Determinize(n)
+	defer d.Close()
+	m, _ := fsm.Minimize(n)
+	defer m.Close()
+
+	if len(m.States()) >= len(d.States()) {
+		t.Errorf("%d states, determinized %d", len(m.States()), len(d.States()))
+	}
+	equivalent(t, d, m, 2)
+}
+
+// pushdown transitions are not taken, as on the empty stack
+func TestDeterminizePushdown(t *testing.T) {
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).AcceptPop(E2, 7, S1).Accept(E1, S0)
+	sm.ConfigState(S1).Final()
+
+	d, _ := fsm.Determinize(sm)
+	defer d.Close()
+	if sm.CanAccept(E2) || d.CanAccept(E2) {
+		t.Errorf("E2 accepted on the empty stack")
+	}
+	if ok, _ := fsm.RunEvents(d, E2); ok {
+		t.Errorf("[E2] is matched")
+	}
+	equivalent(t, sm, d, 3)
+}
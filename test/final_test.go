@@ -0,0 +1,90 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+// accept the binary strings with even 1s
+func TestRunEvents(t *testing.T) {
+	const (
+		_ fsm.State = iota
+		F_even
+		F_odd
+	)
+	const (
+		_ fsm.Event = iota
+		F_0
+		F_1
+		F_x
+	)
+	newMachine := func() fsm.StepFSM {
+		sm := fsm.NewStepFSM(F_even)
+		sm.ConfigState(F_even).Accept(F_0, F_even).Accept(F_1, F_odd).Final()
+		sm.ConfigState(F_odd).Accept(F_0, F_odd).Accept(F_1, F_even)
+		return sm
+	}
+
+	sm := newMachine()
+	ok, path := fsm.RunEvents(sm, F_1, F_0, F_1)
+	if !ok || len(path) != 4 || path[1] != F_odd || path[3] != F_even {
+		t.Errorf("got %v %v", ok, path)
+	}
+	if !sm.IsAccepting() || !sm.Done() {
+		t.Error("not accepting")
+	}
+	sm.Close()
+
+	sm = newMachine()
+	if ok, path := fsm.RunEvents(sm, F_0, F_1); ok || len(path) != 3 {
+		t.Errorf("got %v %v", ok, path)
+	}
+	sm.Close()
+
+	sm = newMachine()
+	if ok, path := fsm.RunEvents(sm, F_0, F_x, F_1); ok || len(path) != 2 {
+		t.Errorf("got %v %v", ok, path)
+	}
+	sm.Close()
+}
+
+func TestAutoDone(t *testing.T) {
+	sm := fsm.NewAutoFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(E1, S1)
+	sm.ConfigState(S1).Accept(E2, S2).Final().OnEnter(fsm.ActionFunc(func() {
+		sm.Pause(E2)
+		if !sm.IsAccepting() || sm.Done() {
+			t.Error("S1 is done with an event fed")
+		}
+	}))
+	sm.ConfigState(S2).Final()
+
+	sm.Start(E1)
+	if !sm.IsAccepting() || sm.Done() {
+		t.Error("paused machine is done")
+	}
+	sm.Resume()
+	if !sm.Done() {
+		t.Error("not done in S2")
+	}
+}
+
+// child completes by entering its final state
+func TestSubmachineFinal(t *testing.T) {
+	child := fsm.NewStepFSM(S0)
+	defer child.Close()
+	child.ConfigState(S0).Accept(E1, S1)
+	child.ConfigState(S1).Final()
+
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(E0, S2)
+	sm.ConfigState(S2).Submachine(child, S3)
+	sm.ConfigState(S3).Final()
+
+	if ok, path := fsm.RunEvents(sm, E0, E1); !ok || path[2] != S3 {
+		t.Errorf("got %v %v", ok, path)
+	}
+}
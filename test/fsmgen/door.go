@@ -0,0 +1,24 @@
+package fsmgen
+
+//go:generate go run github.com/shory152/fsm/cmd/fsmgen -o door_fsm.go testdata/door.json
+
+// actions of Door, which log their names
+var log []string
+
+func shut()   { log = append(log, "shut") }
+func creak()  { log = append(log, "creak") }
+func knock()  { log = append(log, "knock") }
+func dim()    { log = append(log, "dim") }
+func light()  { log = append(log, "light") }
+func click()  { log = append(log, "click") }
+func rattle() { log = append(log, "rattle") }
+
+var actions = map[string]func(){
+	"shut":   shut,
+	"creak":  creak,
+	"knock":  knock,
+	"dim":    dim,
+	"light":  light,
+	"click":  click,
+	"rattle": rattle,
+}
@@ -0,0 +1,171 @@
+// Code generated by fsmgen. DO NOT EDIT.
+
+package fsmgen
+
+import "fmt"
+
+// states of Door
+type DoorState int
+
+const (
+	DoorClosed DoorState = iota
+	DoorOpened
+	DoorLocked
+	DoorBroken
+)
+
+func (v DoorState) String() string {
+	switch v {
+	case DoorClosed:
+		return "Closed"
+	case DoorOpened:
+		return "Opened"
+	case DoorLocked:
+		return "Locked"
+	case DoorBroken:
+		return "Broken"
+	}
+	return fmt.Sprintf("DoorState(%d)", int(v))
+}
+
+// events of Door
+type DoorEvent int
+
+const (
+	DoorOpen DoorEvent = iota
+	DoorLock
+	DoorKnock
+	DoorClose
+	DoorUnlock
+	DoorKick
+)
+
+func (v DoorEvent) String() string {
+	switch v {
+	case DoorOpen:
+		return "Open"
+	case DoorLock:
+		return "Lock"
+	case DoorKnock:
+		return "Knock"
+	case DoorClose:
+		return "Close"
+	case DoorUnlock:
+		return "Unlock"
+	case DoorKick:
+		return "Kick"
+	}
+	return fmt.Sprintf("DoorEvent(%d)", int(v))
+}
+
+// state machine generated from the definition Door
+type Door struct {
+	state DoorState
+}
+
+// a Door in the start state
+func NewDoor() *Door {
+	return &Door{state: DoorClosed}
+}
+
+// the current state
+func (m *Door) Current() DoorState {
+	return m.state
+}
+
+// return to the start state, executing no action
+func (m *Door) Reset() {
+	m.state = DoorClosed
+}
+
+// whether the current state is final
+func (m *Door) IsAccepting() bool {
+	switch m.state {
+	case DoorBroken:
+		return true
+	}
+	return false
+}
+
+// whether ev can be accepted in the current state, true for any
+// event if the state has an otherwise transition
+func (m *Door) CanAccept(ev DoorEvent) bool {
+	switch m.state {
+	case DoorClosed:
+		switch ev {
+		case DoorOpen, DoorLock, DoorKnock, DoorKick:
+			return true
+		}
+	case DoorOpened:
+		switch ev {
+		case DoorOpen, DoorClose, DoorKick:
+			return true
+		}
+	case DoorLocked:
+		switch ev {
+		case DoorUnlock, DoorKick:
+			return true
+		}
+	case DoorBroken:
+		return true
+	}
+	return false
+}
+
+// feed ev, transfer to the next state executing the exit action of
+// the current state, the transition action and the enter action of
+// the next state. it panics if the current state can not accept ev.
+func (m *Door) Step(ev DoorEvent) {
+	switch m.state {
+	case DoorClosed:
+		switch ev {
+		case DoorOpen:
+			creak()
+			m.state = DoorOpened
+			light()
+			return
+		case DoorLock:
+			m.state = DoorLocked
+			return
+		case DoorKnock:
+			knock()
+			return
+		case DoorKick:
+			m.state = DoorBroken
+			return
+		}
+	case DoorOpened:
+		switch ev {
+		case DoorOpen:
+			dim()
+			m.state = DoorOpened
+			light()
+			return
+		case DoorClose:
+			dim()
+			m.state = DoorClosed
+			shut()
+			return
+		case DoorKick:
+			dim()
+			m.state = DoorBroken
+			return
+		}
+	case DoorLocked:
+		switch ev {
+		case DoorUnlock:
+			click()
+			m.state = DoorClosed
+			shut()
+			return
+		case DoorKick:
+			m.state = DoorLocked
+			return
+		}
+	case DoorBroken:
+		rattle()
+		m.state = DoorBroken
+		return
+	}
+	panic(fmt.Sprintf("state %v can not accept the event %v", m.state, ev))
+}
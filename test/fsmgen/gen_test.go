@@ -0,0 +1,225 @@
+package fsmgen
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shory152/fsm"
+	"github.com/shory152/fsm/gen"
+)
+
+func load(t testing.TB) *gen.Definition {
+	f, err := os.Open("testdata/door.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := gen.Load(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// door_fsm.go is the golden file, run go generate to update it
+func TestGolden(t *testing.T) {
+	src, err := gen.Generate(load(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile("door_fsm.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, golden) {
+		t.Errorf("door_fsm.go is out of date, run go generate")
+	}
+}
+
+// step both machines by ev, and return what they do
+func step(m interface{ Step(DoorEvent) }, ev DoorEvent) (s string) {
+	log = log[:0]
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprint("panic: ", r)
+		}
+	}()
+	m.Step(ev)
+	return strings.Join(log, ",")
+}
+
+type interpreted struct {
+	fsm.StepFSM
+}
+
+func (m interpreted) Step(ev DoorEvent) {
+	m.StepFSM.Step(fsm.Event(ev))
+}
+
+func TestEquivalence(t *testing.T) {
+	sm, err := gen.Build(load(t), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sm.Close()
+	door := NewDoor()
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		if i%100 == 0 {
+			sm.Reset()
+			door.Reset()
+		}
+		ev := DoorEvent(rnd.Intn(int(DoorKick) + 1))
+		if door.CanAccept(ev) != sm.CanAccept(fsm.Event(ev)) {
+			t.Fatalf("%v can accept %v: %v, interpreted %v", door.Current(), ev,
+				door.CanAccept(ev), sm.CanAccept(fsm.Event(ev)))
+		}
+		from := door.Current()
+		want := step(interpreted{sm}, ev)
+		if got := step(door, ev); got != want {
+			t.Fatalf("%v -%v->: %q, interpreted %q", from, ev, got, want)
+		}
+		if door.Current().String() != sm.StateName(sm.Current()) ||
+			door.IsAccepting() != sm.IsAccepting() {
+			t.Fatalf("%v -%v-> %v, interpreted %v", from, ev,
+				door.Current(), sm.StateName(sm.Current()))
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	const (
+		S0 fsm.State = iota
+		S1
+		S2
+	)
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.NameState(S1, "Middle")
+	sm.NameEvent(1, "Go")
+	sm.ConfigState(S0).Accept(1, S1).AcceptInternal(4, fsm.ActionFunc(func() {}))
+	sm.ConfigState(S1).Accept(1, S2).Accept(2, S0)
+	sm.ConfigState(S2).Final()
+	sm.AcceptAnyExcept(3, S0, S0)
+	sm.ConfigState(S2).Otherwise(S1)
+
+	d, err := gen.Describe(sm, "machine", "M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := gen.Generate(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, decl := range []string{"MS0 MState = iota", "MMiddle", "MS2", "MGo MEvent = iota", "ME2", "ME3"} {
+		if !bytes.Contains(src, []byte(decl)) {
+			t.Errorf("%q is not declared", decl)
+		}
+	}
+
+	m, err := gen.Build(d, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	names := func(m fsm.StepFSM) string {
+		var b strings.Builder
+		for _, s := range m.States() {
+			for _, tr := range m.Transitions(s) {
+				fmt.Fprintf(&b, "%s-%s->%s ", m.StateName(tr.From), m.EventName(tr.Event), m.StateName(tr.To))
+				if tr.Internal {
+					b.WriteString("internal ")
+				}
+			}
+		}
+		return b.String()
+	}
+	if got, want := names(m), "S0-Go->Middle S0-E4->S0 internal Middle-Go->S2 Middle-E2->S0 Middle-E3->S0 S2-E3->S0 "; got != want {
+		t.Errorf("transitions %v, want %v", got, want)
+	}
+	if fmt.Sprint(m.Finals()) != "[2]" || m.StartState() != S0 {
+		t.Errorf("finals %v, start %v", m.Finals(), m.StartState())
+	}
+	if next, ok := m.Otherwise(S2); !ok || m.StateName(next) != "Middle" {
+		t.Errorf("otherwise of S2 %v", m.StateName(next))
+	}
+	m.ResetTo(S2)
+	if !m.CanAccept(7) {
+		t.Errorf("S2 can not accept any event")
+	}
+
+	pd := fsm.NewStepFSM(S0)
+	defer pd.Close()
+	pd.ConfigState(S0).AcceptPop(1, 7, S1)
+	if _, err := gen.Describe(pd, "machine", "M"); err == nil {
+		t.Errorf("pushdown transition is described")
+	}
+	sub := fsm.NewStepFSM(S0)
+	defer sub.Close()
+	sub.ConfigState(S0).Accept(1, S1)
+	parent := fsm.NewStepFSM(S0)
+	defer parent.Close()
+	parent.ConfigState(S0).Submachine(sub, S1)
+	if _, err := gen.Describe(parent, "machine", "M"); err == nil {
+		t.Errorf("sub-machine is described")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, def := range []string{
+		`{"name": "M", "start": "A", "states": [{"name": "A"}], "extra": 1}`,
+		`{"name": "M", "start": "B", "states": [{"name": "A"}]}`,
+		`{"name": "M", "start": "A", "states": [{"name": "A"}, {"name": "A"}]}`,
+		`{"name": "M", "start": "A", "states": [{"name": "A", "accept": [{"event": "E", "to": "B"}]}]}`,
+		`{"name": "M", "start": "A", "states": [{"name": "A", "accept": [{"event": "E", "to": "A"}, {"event": "E", "to": "A"}]}]}`,
+		`{"name": "M", "start": "A", "states": [{"name": "A", "enter": "a-b"}]}`,
+		`{"name": "1M", "start": "A", "states": [{"name": "A"}]}`,
+		`{"name": "M", "start": "A", "states": [{"name": "A", "enter": "m"}]}`,
+		`{"name": "M", "start": "A", "states": [{"name": "A", "accept": [{"event": "E", "to": "A", "action": "fmt"}]}]}`,
+		`{"name": "M", "start": "A", "states": [{"name": "A", "exit": "ev"}]}`,
+	} {
+		if _, err := gen.Load(strings.NewReader(def)); err == nil {
+			t.Errorf("%s is loaded", def)
+		}
+	}
+
+	d, err := gen.Load(strings.NewReader(`{"name": "M", "start": "A", "states": [{"name": "A", "accept": [{"event": "A", "to": "A"}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Package = "p"
+	if _, err := gen.Generate(d); err == nil {
+		t.Errorf("state A and event A are both generated")
+	}
+	if _, err := gen.Build(d, nil); err != nil {
+		t.Errorf("build %v", err)
+	}
+}
+
+func BenchmarkStepGenerated(b *testing.B) {
+	door := NewDoor()
+	for i := 0; i < b.N; i++ {
+		log = log[:0]
+		door.Step(DoorLock)
+		door.Step(DoorUnlock)
+	}
+}
+
+func BenchmarkStepInterpreted(b *testing.B) {
+	sm, err := gen.Build(load(b), actions)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer sm.Close()
+	sm.Compile()
+	for i := 0; i < b.N; i++ {
+		log = log[:0]
+		sm.Step(fsm.Event(DoorLock))
+		sm.Step(fsm.Event(DoorUnlock))
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+// emit the words separated by blanks, fail on a digit
+func genWords(s string) *fsm.Generator[string] {
+	const (
+		_ fsm.State = iota
+		G_blank
+		G_word
+		G_end
+		G_err
+	)
+	const (
+		_ fsm.Event = iota
+		G_sp
+		G_oc
+		G_eof
+		G_digit
+	)
+
+	in := strings.NewReader(s)
+	var word strings.Builder
+
+	g := fsm.NewGenerator[string](G_blank, G_sp)
+	read := fsm.ActionFunc(func() {
+		c, _, err := in.ReadRune()
+		switch {
+		case err != nil:
+			g.Feed(G_eof)
+		case c == ' ':
+			g.Feed(G_sp)
+		case c >= '0' && c <= '9':
+			g.Feed(G_digit)
+		default:
+			word.WriteRune(c)
+			g.Feed(G_oc)
+		}
+	})
+	emit := fsm.ActionFunc(func() {
+		g.Emit(word.String())
+		word.Reset()
+	})
+
+	g.ConfigState(G_blank).
+		Accept(G_sp, G_blank).
+		Accept(G_oc, G_word).
+		Accept(G_eof, G_end).
+		OnEnter(read)
+	g.ConfigState(G_word).
+		Accept(G_sp, G_blank).
+		Accept(G_oc, G_word).
+		Accept(G_eof, G_end).
+		OnEnter(read).
+		OnExitEvent(G_sp, emit).
+		OnExitEvent(G_eof, emit).
+		OnExitEvent(G_digit, emit)
+	g.ConfigState(G_err).OnEnter(fsm.ActionFunc(func() {
+		g.Fail(errors.New("digit"))
+	}))
+	g.AcceptAny(G_digit, G_err)
+	return g
+}
+
+func TestGenerator(t *testing.T) {
+	var got []string
+	for w, err := range genWords("  ab c  def").All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, w)
+	}
+	if strings.Join(got, ",") != "ab,c,def" {
+		t.Errorf("got %v", got)
+	}
+
+	g := genWords("x y1 z")
+	for _, want := range []string{"x", "y"} {
+		if w, err := g.Next(); err != nil || w != want {
+			t.Fatalf("got %q %v, want %q", w, err, want)
+		}
+	}
+	if _, err := g.Next(); err == nil || err.Error() != "digit" {
+		t.Errorf("got %v, want digit error", err)
+	}
+
+	g = genWords("")
+	if _, err := g.Next(); err != io.EOF {
+		t.Errorf("got %v, want EOF", err)
+	}
+	g.Close()
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+func TestHooks(t *testing.T) {
+	var log []string
+	act := func(name string) fsm.Action {
+		return fsm.ActionFunc(func() {
+			log = append(log, name)
+		})
+	}
+	run := func(sm fsm.StepFSM, ev fsm.Event) string {
+		log = nil
+		sm.Step(ev)
+		return fmt.Sprint(log)
+	}
+
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(E1, S1).Accept(E2, S1)
+	s1 := sm.ConfigState(S1).Accept(E0, S0)
+	s1.OnEnter(act("enter")).
+		AddEnter("low", -1, act("low")).
+		AddEnter("high", 10, act("high")).
+		AddEnter("", 0, act("anon")).
+		OnEnter(act("enter2")) // replaces enter
+	s1.AddEnterFrom(S2, "from S2", 0, act("from S2"))
+	sm.ConfigState(S2).Accept(E1, S1)
+
+	if got := run(sm, E1); got != "[high anon enter2 low]" {
+		t.Errorf("got %v", got)
+	}
+
+	s1.AddEnter("high", 1, act("high2")) // replaces high
+	s1.RemoveAction("low")
+	run(sm, E0)
+	if got := run(sm, E1); got != "[high2 anon enter2]" {
+		t.Errorf("got %v", got)
+	}
+
+	// specific ones from S2
+	sm.ConfigState(S1).Accept(E2, S2)
+	run(sm, E2)
+	for _, c := range []struct {
+		p    fsm.HookPolicy
+		want string
+	}{
+		{fsm.SpecificInstead, "[from S2]"},
+		{fsm.SpecificBefore, "[from S2 high2 anon enter2]"},
+		{fsm.SpecificAfter, "[high2 anon enter2 from S2]"},
+	} {
+		s1.Specific(c.p)
+		if got := run(sm, E1); got != c.want {
+			t.Errorf("policy %v got %v, want %v", c.p, got, c.want)
+		}
+		run(sm, E2)
+	}
+}
+
+func TestEdgeHooks(t *testing.T) {
+	var log []string
+	act := func(name string) fsm.Action {
+		return fsm.ActionFunc(func() {
+			log = append(log, name)
+		})
+	}
+
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).
+		Accept(E1, S1).Accept(E2, S2).Accept(E3, S2).
+		OnExit(act("exit")).
+		OnExitTo(S2, act("exit to S2")).
+		OnExitEvent(E3, act("exit by E3"))
+	sm.ConfigState(S1).Accept(E1, S2).Accept(E0, S0)
+	sm.ConfigState(S2).Accept(E0, S0).
+		OnEnter(act("enter")).
+		OnEnterVia(E1, act("enter via E1")).
+		OnEnterFrom(S0, act("enter from S0"))
+
+	for _, c := range []struct {
+		evs  []fsm.Event
+		want string
+	}{
+		{[]fsm.Event{E1, E1}, "[exit enter via E1]"},
+		{[]fsm.Event{E2}, "[exit to S2 enter from S0]"},
+		{[]fsm.Event{E3}, "[exit by E3 enter from S0]"},
+	} {
+		log = nil
+		for _, ev := range c.evs {
+			sm.Step(ev)
+		}
+		if got := fmt.Sprint(log); got != c.want {
+			t.Errorf("%v got %v, want %v", c.evs, got, c.want)
+		}
+		sm.Step(E0)
+	}
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode"
+
+	"github.com/shory152/fsm"
+)
+
+func TestInputFSM(t *testing.T) {
+	const (
+		_ fsm.State = iota
+		I_word
+		I_end
+		I_err
+		I_rerr
+	)
+	const (
+		_ fsm.Event = iota
+		I_letter
+		I_digit
+		I_line
+		I_oc
+		I_eof
+		I_ioerr
+	)
+	classify := func(c rune) fsm.Event {
+		switch {
+		case unicode.IsLetter(c):
+			return I_letter
+		case unicode.IsDigit(c):
+			return I_digit
+		case c == '\n':
+			return I_line
+		}
+		return I_oc
+	}
+
+	var words []string
+	var word strings.Builder
+	var errPos fsm.Position
+	var errRune rune
+
+	build := func(in io.RuneScanner) fsm.InputFSM {
+		words = nil
+		word.Reset()
+		sm := fsm.NewInputFSM(I_word, in, classify)
+		sm.OnEOF(I_eof)
+		sm.OnReadError(I_ioerr)
+		sm.ConfigState(I_word).
+			Accept(I_letter, I_word).
+			Accept(I_line, I_word).
+			Accept(I_eof, I_end).
+			Accept(I_oc, I_err).
+			OnEnter(fsm.ActionFunc(func() {
+				if c := sm.Rune(); c == '\n' {
+					words = append(words, word.String())
+					word.Reset()
+				} else if unicode.IsLetter(c) {
+					word.WriteRune(c)
+				}
+				// a word never ends by a digit, look ahead
+				if c, err := sm.Peek(); err == nil && unicode.IsDigit(c) {
+					word.WriteRune('#')
+				}
+			}))
+		sm.ConfigState(I_end).OnEnter(fsm.ActionFunc(func() {
+			words = append(words, word.String())
+		}))
+		sm.ConfigState(I_err).OnEnter(fsm.ActionFunc(func() {
+			errPos, errRune = sm.Pos(), sm.Rune()
+			sm.Stop()
+		}))
+		sm.ConfigState(I_rerr).OnEnter(fsm.ActionFunc(func() {
+			errPos = sm.Pos()
+		}))
+		sm.AcceptAny(I_ioerr, I_rerr)
+		return sm
+	}
+
+	sm := build(strings.NewReader("ab\nc"))
+	if err := sm.Run(); err != nil {
+		t.Fatal(err)
+	}
+	sm.Close()
+	if strings.Join(words, ",") != "ab,c" {
+		t.Errorf("got words %v", words)
+	}
+
+	sm = build(strings.NewReader("ab\nc大d!x"))
+	if err := sm.Run(); err != nil {
+		t.Fatal(err)
+	}
+	sm.Close()
+	want := fsm.Position{Offset: 8, Line: 2, Column: 4}
+	if errRune != '!' || errPos != want {
+		t.Errorf("error at %q %+v, want '!' %+v", errRune, errPos, want)
+	}
+
+	sm = build(strings.NewReader("a2"))
+	if err := sm.Run(); err == nil {
+		t.Error("digit is accepted")
+	}
+	if len(words) != 0 || word.String() != "a#" {
+		t.Errorf("got %v %q", words, word.String())
+	}
+	sm.Close()
+
+	ioerr := errors.New("broken")
+	sm = build(bufio.NewReader(io.MultiReader(strings.NewReader("x\ny"), iotest.ErrReader(ioerr))))
+	if err := sm.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if sm.Err() != ioerr || errPos.Line != 2 || errPos.Column != 2 {
+		t.Errorf("got %v at %v", sm.Err(), errPos)
+	}
+	sm.Close()
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+func TestInspect(t *testing.T) {
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(E2, S2).Accept(E1, S1)
+	sm.ConfigState(S1).AcceptPop(E3, 7, S3).AcceptPush(E4, 7, S1)
+	sm.ConfigState(S2).Otherwise(S0)
+	sm.AcceptAnyExcept(E5, S5, S5)
+	sm.AcceptAny(E1, S4)
+
+	if got := fmt.Sprint(sm.States()); got != "[0 1 2 3 4 5]" {
+		t.Errorf("states %v", got)
+	}
+	if got := fmt.Sprint(sm.Transitions(S0)); got != "[{0 1 1} {0 2 2} {0 5 5}]" {
+		t.Errorf("transitions of S0 %v", got)
+	}
+	if got := fmt.Sprint(sm.Transitions(S5)); got != "[{5 1 4}]" {
+		t.Errorf("transitions of S5 %v", got)
+	}
+	if got := fmt.Sprint(sm.Transitions(S1)); got != "[{1 1 4} {1 3 3 pop 7} {1 4 1 push 7} {1 5 5}]" {
+		t.Errorf("transitions of S1 %v", got)
+	}
+	if tr := sm.Transitions(S1)[1]; tr.Op != fsm.StackPop || tr.Sym != 7 {
+		t.Errorf("pop transition %+v", tr)
+	}
+	if next, ok := sm.Otherwise(S2); !ok || next != S0 {
+		t.Errorf("otherwise of S2 %v %v", next, ok)
+	}
+	sm.ConfigState(S3).AcceptReturn(E0)
+	if got := fmt.Sprint(sm.Transitions(S3)); got != "[{3 0 3 return -2} {3 1 4} {3 5 5}]" {
+		t.Errorf("transitions of S3 %v", got)
+	}
+	sm.ConfigState(S4).AcceptInternal(E2, fsm.ActionFunc(func() {}))
+	if got := fmt.Sprint(sm.Transitions(S4)); got != "[{4 1 4} {4 2 4 internal} {4 5 5}]" {
+		t.Errorf("transitions of S4 %v", got)
+	}
+	if _, ok := sm.Otherwise(S1); ok || sm.HasSubmachine(S1) {
+		t.Errorf("S1 has otherwise or sub-machine")
+	}
+
+	if sm.Current() != S0 || fmt.Sprint(sm.AcceptedEvents()) != "[1 2 5]" {
+		t.Errorf("in %v accept %v", sm.Current(), sm.AcceptedEvents())
+	}
+	sm.Step(E1)
+	if got := fmt.Sprint(sm.AcceptedEvents()); got != "[1 4 5]" || sm.CanAccept(E3) {
+		t.Errorf("in S1 accept %v", got)
+	}
+	sm.Step(E4)
+	if got := fmt.Sprint(sm.AcceptedEvents()); got != "[1 3 4 5]" || !sm.CanAccept(E3) {
+		t.Errorf("in S1 with 7 pushed accept %v", got)
+	}
+	sm.Step(E3)
+	if sm.Current() != S3 {
+		t.Errorf("current %v", sm.Current())
+	}
+}
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+func TestInternal(t *testing.T) {
+	enter, exit, ticks := 0, 0, 0
+
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).
+		Accept(E1, S0).
+		AcceptInternal(E2, fsm.ActionFunc(func() {
+			ticks++
+		})).
+		OnEnter(fsm.ActionFunc(func() {
+			enter++
+		})).
+		OnExit(fsm.ActionFunc(func() {
+			exit++
+		})).
+		Output("s0")
+
+	sm.Step(E1) // external, exits and enters again
+	if out := sm.Step(E2); out != "s0" {
+		t.Errorf("output %v", out)
+	}
+	sm.Step(E2)
+
+	if enter != 1 || exit != 1 || ticks != 2 || sm.Current() != S0 {
+		t.Errorf("enter %v exit %v ticks %v in %v", enter, exit, ticks, sm.Current())
+	}
+}
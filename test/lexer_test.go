@@ -0,0 +1,86 @@
+package test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/shory152/fsm"
+)
+
+// split "<name>text</name>" like input into tags and texts
+func TestLexer(t *testing.T) {
+	const (
+		_ fsm.State = iota
+		L_start
+		L_tag
+		L_gt
+		L_text
+		L_end
+		L_err
+	)
+
+	var tokens []string
+	var val strings.Builder
+	flush := fsm.ActionFunc(func() {
+		if val.Len() > 0 {
+			tokens = append(tokens, val.String())
+			val.Reset()
+		}
+	})
+
+	lx := fsm.NewLexer(L_start)
+	defer lx.Close()
+	keep := fsm.ActionFunc(func() {
+		val.WriteRune(lx.Rune())
+	})
+
+	lx.ConfigState(L_start).
+		AcceptRune(fsm.Space, L_start).
+		AcceptRune(fsm.Rune('<'), L_tag).
+		AcceptEOF(L_end).
+		Otherwise(L_err)
+	lx.ConfigState(L_tag).
+		AcceptRune(fsm.Rune('>'), L_gt).
+		AcceptRune(fsm.Not(fsm.RuneSet("<\n")), L_tag).
+		OnEnter(keep).
+		OnEnterFrom(L_text, fsm.ActionFunc(func() {
+			flush.Do()
+			keep.Do()
+		}))
+	lx.ConfigState(L_gt).
+		AcceptRune(fsm.Rune('<'), L_tag).
+		AcceptRune(fsm.AnyRune, L_text).
+		AcceptEOF(L_end).
+		OnEnter(fsm.ActionFunc(func() {
+			keep.Do()
+			flush.Do()
+		}))
+	lx.ConfigState(L_text).
+		AcceptRune(fsm.Rune('<'), L_tag).
+		AcceptRune(fsm.InTable(unicode.Letter, unicode.Digit, unicode.Space), L_text).
+		AcceptEOF(L_end).
+		OnEnter(keep)
+	lx.ConfigState(L_end).OnEnter(flush)
+	lx.ConfigState(L_err).OnEnter(fsm.ActionFunc(func() {
+		lx.Stop()
+	}))
+	lx.AcceptAny(fsm.EventRune, L_err)
+
+	err := lx.Run(strings.NewReader(" <a>hi 1</a>\n<b>x</b>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<a>|hi 1|</a>|\n|<b>|x|</b>"
+	if got := strings.Join(tokens, "|"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	lx2 := fsm.NewLexer(L_start)
+	defer lx2.Close()
+	lx2.ConfigState(L_start).AcceptRune(fsm.RuneRange('a', 'z'), L_start)
+	if err := lx2.Run(strings.NewReader("abc")); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v, want unexpected EOF", err)
+	}
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+const (
+	N_idle fsm.State = 100 + iota
+	N_busy
+)
+const (
+	N_go fsm.Event = 100 + iota
+	N_halt
+)
+
+func TestNames(t *testing.T) {
+	if got := fmt.Sprint(N_idle, N_halt, S1); got != "100 101 1" {
+		t.Errorf("got %q", got)
+	}
+
+	type config struct {
+		Start fsm.State
+		On    []fsm.Event
+	}
+	b, err := json.Marshal(config{N_busy, []fsm.Event{N_go, E2}})
+	if err != nil || string(b) != `{"Start":"101","On":["100","2"]}` {
+		t.Errorf("got %s %v", b, err)
+	}
+	var c config
+	if err := json.Unmarshal([]byte(`{"Start":"100","On":["101","3"]}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Start != N_idle || len(c.On) != 2 || c.On[0] != N_halt || c.On[1] != E3 {
+		t.Errorf("got %+v", c)
+	}
+	if err := json.Unmarshal([]byte(`{"Start":"nap"}`), &c); err == nil {
+		t.Error("bad state is parsed")
+	}
+
+	sm := fsm.NewStepFSM(N_idle)
+	defer sm.Close()
+	sm.ConfigState(N_idle).Accept(N_go, N_busy)
+	sm.NameStates(N_idle, "idle", "busy")
+	sm.NameEvents(N_go, "go", "halt")
+	sm.NameState(N_idle, "waiting")
+	sm.NameEvent(E4, "kick")
+	if sm.StateName(N_idle) != "waiting" || sm.StateName(N_busy) != "busy" || sm.EventName(E4) != "kick" {
+		t.Errorf("got %v %v %v", sm.StateName(N_idle), sm.StateName(N_busy), sm.EventName(E4))
+	}
+	other := fsm.NewStepFSM(N_idle)
+	defer other.Close()
+	if other.StateName(N_busy) != "101" || other.EventName(N_halt) != "101" {
+		t.Errorf("names leak to another machine: %v %v", other.StateName(N_busy), other.EventName(N_halt))
+	}
+
+	defer func() {
+		msg := fmt.Sprint(recover())
+		if !strings.Contains(msg, "waiting") || !strings.Contains(msg, "kick") {
+			t.Errorf("panic %q", msg)
+		}
+	}()
+	sm.Step(E4)
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+const S6 fsm.State = 6
+
+// events a and b of the words matched
+const (
+	Ea fsm.Event = iota
+	Eb
+)
+
+func abWord(evs []fsm.Event) string {
+	w := make([]byte, len(evs))
+	for i, ev := range evs {
+		w[i] = "ab"[ev]
+	}
+	return string(w)
+}
+
+// (a|b)*ab(a|b)? | a*b*
+func abNFA() fsm.NFA {
+	n := fsm.NewNFA(S0)
+	n.ConfigState(S0).Epsilon(S1, S4)
+	n.ConfigState(S1).Accept(Ea, S1, S2).Accept(Eb, S1)
+	n.ConfigState(S2).Accept(Eb, S3)
+	n.ConfigState(S3).Final().Accept(Ea, S6).Accept(Eb, S6)
+	n.ConfigState(S4).Final().Accept(Ea, S4).Epsilon(S5)
+	n.ConfigState(S5).Final().Accept(Eb, S5)
+	n.ConfigState(S6).Final()
+	return n
+}
+
+func TestNFA(t *testing.T) {
+	n := abNFA()
+	defer n.Close()
+
+	if got := fmt.Sprint(n.Active()); got != "[0 1 4 5]" || !n.IsAccepting() {
+		t.Errorf("start in %v", got)
+	}
+	n.Step(Ea)
+	if got := fmt.Sprint(n.Active()); got != "[1 2 4 5]" {
+		t.Errorf("a in %v", got)
+	}
+	n.Step(Eb)
+	if got := fmt.Sprint(n.Active()); got != "[1 3 5]" || !n.IsAccepting() {
+		t.Errorf("ab in %v", got)
+	}
+	n.Step(Ea)
+	if got := fmt.Sprint(n.Active()); got != "[1 2 6]" || !n.IsAccepting() || !n.CanAccept(Ea) {
+		t.Errorf("aba in %v", got)
+	}
+	n.Step(Ea)
+	if got := fmt.Sprint(n.Active()); got != "[1 2]" || n.IsAccepting() {
+		t.Errorf("abaa in %v", got)
+	}
+
+	if got := fmt.Sprint(n.Transitions(S1)); got != "[{1 0 1} {1 0 2} {1 1 1}]" {
+		t.Errorf("transitions of S1 %v", got)
+	}
+	if got := fmt.Sprint(n.Epsilons(S0)); got != "[1 4]" {
+		t.Errorf("epsilons of S0 %v", got)
+	}
+	if got := fmt.Sprint(n.Finals()); got != "[3 4 5 6]" {
+		t.Errorf("finals %v", got)
+	}
+}
+
+func TestNFAMatch(t *testing.T) {
+	n := abNFA()
+	defer n.Close()
+	re := regexp.MustCompile(`^((a|b)*ab(a|b)?|a*b*)$`)
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		evs := make([]fsm.Event, rnd.Intn(8))
+		for j := range evs {
+			evs[j] = fsm.Event(rnd.Intn(2))
+		}
+		if got, want := n.Match(evs...), re.MatchString(abWord(evs)); got != want {
+			t.Fatalf("match %q %v, want %v", abWord(evs), got, want)
+		}
+	}
+}
+
+func TestNFADead(t *testing.T) {
+	n := fsm.NewNFA(S0)
+	defer n.Close()
+	n.ConfigState(S0).Accept(Ea, S1)
+	n.ConfigState(S1).Final()
+
+	if n.Step(Eb) || len(n.Active()) != 0 || n.CanAccept(Ea) {
+		t.Errorf("b in %v", n.Active())
+	}
+	if n.Step(Ea) {
+		t.Errorf("dead machine steps to %v", n.Active())
+	}
+	n.Reset()
+	if !n.Step(Ea) || !n.IsAccepting() {
+		t.Errorf("a after reset in %v", n.Active())
+	}
+}
+
+func BenchmarkNFAStep(b *testing.B) {
+	n := abNFA()
+	defer n.Close()
+	n.Reset()
+	for i := 0; i < b.N; i++ {
+		n.Step(Ea)
+		n.Step(Eb)
+	}
+}
@@ -0,0 +1,42 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+func TestOtherwise(t *testing.T) {
+	var others []fsm.Event
+	entered := 0
+
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+
+	s0 := sm.ConfigState(S0)
+	s0.Accept(E1, S1)
+	s0.Otherwise(S2)
+	s0.OnOtherwise(fsm.EventActionFunc(func(e fsm.Event) {
+		others = append(others, e)
+	}))
+	sm.ConfigState(S1).Accept(E0, S0)
+	sm.ConfigState(S2).Accept(E0, S0).OnEnter(fsm.ActionFunc(func() {
+		entered++
+	}))
+	sm.AcceptAny(E5, S5)
+
+	sm.Step(E3) // S0 -> S2 by otherwise
+	sm.Step(E0)
+	sm.Step(E1) // S0 -> S1, not otherwise
+	sm.Step(E0)
+	sm.Step(E4) // S0 -> S2 by otherwise
+	sm.Step(E0)
+	sm.Step(E5) // S0 -> S5, wildcard wins
+
+	if entered != 2 {
+		t.Errorf("entered S2 %v times, want 2", entered)
+	}
+	if len(others) != 2 || others[0] != E3 || others[1] != E4 {
+		t.Errorf("otherwise action got %v, want [%v %v]", others, E3, E4)
+	}
+}
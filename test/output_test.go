@@ -0,0 +1,56 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+const (
+	_ fsm.Event = iota
+	O_0
+	O_1
+)
+
+// Moore: output the parity of 1s read
+func TestMooreOutput(t *testing.T) {
+	const (
+		_ fsm.State = iota
+		O_even
+		O_odd
+	)
+	sm := fsm.NewStepFSM(O_even)
+	defer sm.Close()
+	sm.ConfigState(O_even).Accept(O_0, O_even).Accept(O_1, O_odd).Output("even")
+	sm.ConfigState(O_odd).Accept(O_0, O_odd).Accept(O_1, O_even).Output("odd")
+
+	if sm.Output() != "even" {
+		t.Errorf("initial output %v", sm.Output())
+	}
+	ok, outs := fsm.Transduce(sm, O_1, O_0, O_1, O_1)
+	if got := fmt.Sprint(outs); !ok || got != "[odd odd even odd]" {
+		t.Errorf("got %v %v", got, ok)
+	}
+}
+
+// Mealy: output 1 on a rising edge, 0 otherwise
+func TestMealyOutput(t *testing.T) {
+	const (
+		_ fsm.State = iota
+		O_low
+		O_high
+	)
+	sm := fsm.NewStepFSM(O_low)
+	defer sm.Close()
+	sm.ConfigState(O_low).AcceptOutput(O_0, O_low, 0).AcceptOutput(O_1, O_high, 1)
+	sm.ConfigState(O_high).AcceptOutput(O_0, O_low, 0).AcceptOutput(O_1, O_high, 0)
+
+	if out := sm.Step(O_1); out != 1 {
+		t.Errorf("got %v", out)
+	}
+	ok, outs := fsm.Transduce(sm, O_1, O_0, O_1, O_1, O_0, E5)
+	if got := fmt.Sprint(outs); ok || got != "[0 0 1 0 0]" {
+		t.Errorf("got %v %v", got, ok)
+	}
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+// recognize balanced "()" and "[]"
+func TestPushdown(t *testing.T) {
+	const (
+		_ fsm.State = iota
+		P_in
+		P_ok
+		P_err
+	)
+	const (
+		_ fsm.Event = iota
+		P_lp
+		P_rp
+		P_lb
+		P_rb
+		P_end
+	)
+	const (
+		_ fsm.Symbol = iota
+		P_paren
+		P_bracket
+	)
+	events := map[rune]fsm.Event{'(': P_lp, ')': P_rp, '[': P_lb, ']': P_rb}
+
+	check := func(s string) (ok bool, depth int) {
+		sm := fsm.NewStepFSM(P_in)
+		defer sm.Close()
+		sm.ConfigState(P_in).
+			AcceptPush(P_lp, P_paren, P_in).
+			AcceptPush(P_lb, P_bracket, P_in).
+			AcceptPop(P_rp, P_paren, P_in).
+			AcceptPop(P_rb, P_bracket, P_in).
+			AcceptIfTop(P_end, fsm.EmptyStack, P_ok).
+			Otherwise(P_err)
+		sm.ConfigState(P_ok).OnEnter(fsm.ActionFunc(func() {
+			ok = true
+		}))
+		sm.ConfigState(P_err).Otherwise(P_err)
+
+		for _, c := range s {
+			sm.Step(events[c])
+		}
+		depth = sm.Depth()
+		sm.Step(P_end)
+		return
+	}
+
+	cases := []struct {
+		s     string
+		ok    bool
+		depth int
+	}{
+		{"", true, 0},
+		{"([]())", true, 0},
+		{"([)]", false, 2},
+		{"((", false, 2},
+		{"())", false, 0},
+	}
+	for _, c := range cases {
+		if ok, depth := check(c.s); ok != c.ok || depth != c.depth {
+			t.Errorf("%q: got %v %v, want %v %v", c.s, ok, depth, c.ok, c.depth)
+		}
+	}
+}
+
+// S0 calls the sub-machine S1 -> S2 twice, returning to S3 and S4
+func TestPushdownCall(t *testing.T) {
+	var path []fsm.State
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	for _, s := range []fsm.State{S1, S2, S3, S4} {
+		s := s
+		sm.ConfigState(s).OnEnter(fsm.ActionFunc(func() {
+			path = append(path, s)
+		}))
+	}
+	sm.ConfigState(S0).AcceptCall(E1, S1, S3)
+	sm.ConfigState(S1).Accept(E2, S2)
+	sm.ConfigState(S2).AcceptReturn(E3)
+	sm.ConfigState(S3).AcceptCall(E1, S1, S4)
+
+	sm.Step(E1)
+	if top, ok := sm.Top(); !ok || top != fsm.CallFrame {
+		t.Errorf("top %v %v", top, ok)
+	}
+	sm.Step(E2)
+	sm.Step(E3)
+	sm.Step(E1)
+	sm.Step(E2)
+	sm.Step(E3)
+
+	want := []fsm.State{S1, S2, S3, S1, S2, S4}
+	if len(path) != len(want) || sm.Depth() != 0 {
+		t.Fatalf("path %v, depth %v", path, sm.Depth())
+	}
+	for i := range want {
+		if path[i] != want[i] {
+			t.Fatalf("path %v, want %v", path, want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+func TestFreeze(t *testing.T) {
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	s0 := sm.ConfigState(S0).Accept(E1, S1)
+	sm.Freeze()
+
+	for name, config := range map[string]func(){
+		"ConfigState": func() { sm.ConfigState(S2) },
+		"Accept":      func() { s0.Accept(E2, S2) },
+		"OnEnter":     func() { s0.OnEnter(fsm.ActionFunc(func() {})) },
+		"AcceptAny":   func() { sm.AcceptAny(E3, S0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v does not panic", name)
+				}
+			}()
+			config()
+		}()
+	}
+
+	sm.Step(E1)
+	if sm.Current() != S1 {
+		t.Errorf("in %v", sm.Current())
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(E1, S1)
+	sm.ConfigState(S1).Accept(E0, S0)
+	sm.Freeze()
+
+	err := sm.Reconfigure(func(b fsm.Builder) {
+		b.ConfigState(S1).Accept(E2, S2)
+	})
+	if err == nil {
+		t.Error("start state is not validated")
+	}
+
+	entered := 0
+	sm.Step(E1)
+	err = sm.Reconfigure(func(b fsm.Builder) {
+		b.ConfigState(S0).Accept(E1, S1)
+		b.ConfigState(S1).Accept(E2, S2)
+		b.ConfigState(S2).OnEnter(fsm.ActionFunc(func() {
+			entered++
+		}))
+		b.AcceptAny(E0, S0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.Step(E2)
+	if sm.Current() != S2 || entered != 1 {
+		t.Errorf("in %v, entered %v", sm.Current(), entered)
+	}
+	sm.Step(E0)
+
+	// the table is frozen too
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("new table is not frozen")
+			}
+		}()
+		sm.ConfigState(S3)
+	}()
+
+	// the current state is missing
+	sm.Step(E1)
+	sm.Reconfigure(func(b fsm.Builder) {
+		b.ConfigState(S0).Accept(E2, S2)
+	})
+	if _, err := sm.TryStep(E0); err == nil || sm.Current() != S1 {
+		t.Errorf("got %v in %v", err, sm.Current())
+	}
+	if _, err := sm.TryStep(E0); err != nil || sm.Current() != S0 {
+		t.Errorf("old table is not kept, got %v in %v", err, sm.Current())
+	}
+}
+
+func TestReconfigureRunning(t *testing.T) {
+	sm := fsm.NewAutoFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(E1, S1)
+	sm.ConfigState(S1).Accept(E1, S1).OnEnter(fsm.ActionFunc(func() {
+		sm.Reconfigure(func(b fsm.Builder) {
+			b.ConfigState(S0)
+			b.ConfigState(S1).Accept(E1, S2)
+			b.ConfigState(S2).OnEnter(fsm.ActionFunc(func() {
+				sm.Stop()
+			}))
+		})
+		sm.Feed(E1)
+	}))
+
+	sm.Start(E1)
+	if sm.Current() != S2 {
+		t.Errorf("in %v", sm.Current())
+	}
+}
+
+// an action reconfigures the next state, which is looked up by the
+// new table
+func TestReconfigureInput(t *testing.T) {
+	classify := func(c rune) fsm.Event {
+		return fsm.Event(c)
+	}
+	sm := fsm.NewInputFSM(S0, strings.NewReader("ac"), classify)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept('a', S1)
+	sm.ConfigState(S1).Accept('b', S2).OnEnter(fsm.ActionFunc(func() {
+		sm.Reconfigure(func(b fsm.Builder) {
+			b.ConfigState(S0).Accept('a', S1)
+			b.ConfigState(S1).Accept('c', S2)
+			b.ConfigState(S2)
+		})
+	}))
+	if err := sm.Run(); err != nil || sm.Current() != S2 {
+		t.Errorf("got %v in %v", err, sm.Current())
+	}
+
+	step := fsm.NewStepFSM(S0)
+	defer step.Close()
+	step.ConfigState(S0).Accept(E1, S1)
+	step.ConfigState(S1)
+	step.Reconfigure(func(b fsm.Builder) {
+		b.ConfigState(S0).Accept(E2, S1)
+		b.ConfigState(S1).Final()
+	})
+	if step.CanAccept(E1) || !step.CanAccept(E2) {
+		t.Errorf("accepted %v", step.AcceptedEvents())
+	}
+	if ok, path := fsm.RunEvents(step, E2); !ok || len(path) != 2 {
+		t.Errorf("got %v %v", ok, path)
+	}
+}
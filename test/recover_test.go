@@ -0,0 +1,74 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+func TestRecover(t *testing.T) {
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.Recover()
+	sm.ConfigState(S0).AcceptPush(E1, 1, S1).Accept(E2, S2)
+	sm.ConfigState(S1).OnEnter(fsm.ActionFunc(func() {
+		panic("enter S1")
+	})).OnEnterFail(fsm.FailRevert)
+	sm.ConfigState(S2).OnEnter(fsm.ActionFunc(func() {
+		panic(errBoom)
+	}))
+
+	_, err := sm.TryStep(E1)
+	var te *fsm.TransitionError
+	var pe *fsm.PanicError
+	if !errors.As(err, &te) || !errors.As(err, &pe) {
+		t.Fatalf("got %v", err)
+	}
+	if te.From != S0 || te.Event != E1 || te.To != S1 || pe.Value != "enter S1" {
+		t.Errorf("got %v", err)
+	}
+	if !strings.Contains(string(pe.Stack), "TestRecover") {
+		t.Errorf("stack %s", pe.Stack)
+	}
+	if sm.Current() != S0 || sm.Depth() != 0 {
+		t.Errorf("not reverted, in %v depth %v", sm.Current(), sm.Depth())
+	}
+
+	// stays in S2 by default
+	if _, err := sm.TryStep(E2); !errors.Is(err, errBoom) || sm.Current() != S2 {
+		t.Errorf("got %v in %v", err, sm.Current())
+	}
+}
+
+func TestRecoverTo(t *testing.T) {
+	var got any
+	sm := fsm.NewAutoFSM(S0)
+	defer sm.Close()
+	sm.RecoverTo(S5)
+	sm.ConfigState(S0).Accept(E1, S1)
+	sm.ConfigState(S1).Accept(E2, S2).OnEnter(fsm.ActionFunc(func() {
+		sm.Feed(E2)
+	}))
+	sm.ConfigState(S2).OnExit(fsm.ActionFunc(func() {
+		panic("exit S2")
+	})).Accept(E3, S3).OnEnter(fsm.ActionFunc(func() {
+		sm.Feed(E3)
+	}))
+	sm.ConfigState(S5).Accept(E0, S0).OnEnter(fsm.ActionFunc(func() {
+		var pe *fsm.PanicError
+		if errors.As(sm.Err(), &pe) {
+			got = pe.Value
+		}
+		sm.Feed(E0)
+	}))
+	sm.ConfigState(S0).OnEnterFrom(S5, fsm.ActionFunc(func() {
+		sm.Stop()
+	}))
+
+	sm.Start(E1)
+	if got != "exit S2" || sm.Current() != S0 {
+		t.Errorf("got %v in %v", got, sm.Current())
+	}
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+func TestReset(t *testing.T) {
+	entered := 0
+	sm := fsm.NewAutoFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(E1, S1).AcceptPush(E2, 1, S2)
+	sm.ConfigState(S1).Accept(E2, S2).OnEnter(fsm.ActionFunc(func() {
+		entered++
+		sm.Feed(E2)
+	}))
+	sm.ConfigState(S2).Accept(E1, S1).OnEnter(fsm.ActionFunc(func() {
+		sm.Feed(E1)
+		sm.Stop()
+	}))
+
+	sm.Start(E1)
+	if sm.Current() != S2 || entered != 1 {
+		t.Fatalf("in %v entered %v", sm.Current(), entered)
+	}
+
+	sm.Reset()
+	if sm.Current() != S0 || sm.Done() {
+		t.Errorf("reset in %v", sm.Current())
+	}
+	sm.Start(E2)
+	if sm.Current() != S2 || sm.Depth() != 1 || entered != 1 {
+		t.Errorf("in %v depth %v entered %v", sm.Current(), sm.Depth(), entered)
+	}
+
+	sm.ResetTo(S2)
+	sm.Start(E1)
+	if sm.Current() != S2 || sm.Depth() != 0 || entered != 2 {
+		t.Errorf("in %v depth %v entered %v", sm.Current(), sm.Depth(), entered)
+	}
+}
+
+func TestResetInput(t *testing.T) {
+	words := 0
+	sm := fsm.NewInputFSM(S0, strings.NewReader("ab c"), func(r rune) fsm.Event {
+		if r == ' ' {
+			return E0
+		}
+		return E1
+	})
+	defer sm.Close()
+	sm.OnEOF(E2)
+	sm.ConfigState(S0).Accept(E1, S1).Accept(E0, S0).Accept(E2, S2)
+	sm.ConfigState(S1).Accept(E1, S1).Accept(E0, S0).Accept(E2, S2).
+		OnEnterFrom(S0, fsm.ActionFunc(func() {
+			words++
+		}))
+
+	for _, in := range []string{"ab c", " a b c d"} {
+		sm.ResetInput(strings.NewReader(in))
+		if err := sm.Run(); err != nil || sm.Current() != S2 {
+			t.Errorf("%q: got %v in %v", in, err, sm.Current())
+		}
+	}
+	if words != 6 {
+		t.Errorf("got %v words", words)
+	}
+}
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+const (
+	_ fsm.Event = iota
+	Q_quot
+	Q_eq
+	Q_sp
+	Q_oc
+)
+
+func quoteEvent(c rune) fsm.Event {
+	switch c {
+	case '"':
+		return Q_quot
+	case '=':
+		return Q_eq
+	case ' ':
+		return Q_sp
+	}
+	return Q_oc
+}
+
+// machine reading a quoted value, shared by the parents
+func quotedValue(val *strings.Builder, c *rune) (fsm.StepFSM, fsm.State) {
+	const (
+		_ fsm.State = iota
+		Q_open
+		Q_in
+		Q_done
+	)
+	sm := fsm.NewStepFSM(Q_open)
+	sm.ConfigState(Q_open).Accept(Q_quot, Q_in)
+	sm.ConfigState(Q_in).
+		Accept(Q_quot, Q_done).
+		Otherwise(Q_in).
+		OnEnter(fsm.ActionFunc(func() {
+			val.WriteRune(*c)
+		})).
+		OnEnterFrom(Q_open, fsm.ActionFunc(func() {
+			val.Reset()
+		}))
+	return sm, Q_done
+}
+
+// parse k="v" pairs separated by blanks
+func TestSubmachine(t *testing.T) {
+	const (
+		_ fsm.State = iota
+		K_key
+		K_val
+		K_after
+	)
+
+	var c rune
+	var key, val strings.Builder
+	var pairs []string
+
+	child, done := quotedValue(&val, &c)
+	defer child.Close()
+
+	sm := fsm.NewStepFSM(K_key)
+	defer sm.Close()
+	sm.ConfigState(K_key).
+		Accept(Q_eq, K_val).
+		Accept(Q_oc, K_key).
+		OnEnter(fsm.ActionFunc(func() {
+			key.WriteRune(c)
+		}))
+	sm.ConfigState(K_val).Submachine(child, K_after, done)
+	sm.ConfigState(K_after).
+		Accept(Q_sp, K_key).
+		OnEnter(fsm.ActionFunc(func() {
+			pairs = append(pairs, key.String()+":"+val.String())
+			key.Reset()
+		}))
+	// skip the blank
+	sm.ConfigState(K_key).OnEnterFrom(K_after, fsm.ActionFunc(func() {}))
+
+	for _, c = range `a="x y" bc="=" d=""` {
+		sm.Step(quoteEvent(c))
+	}
+	if got := strings.Join(pairs, ","); got != "a:x y,bc:=,d:" {
+		t.Errorf("got %q", got)
+	}
+}
+
+// the child runs at once when it is entered, and completes by Stop
+func TestAutoSubmachine(t *testing.T) {
+	var path []string
+	child := fsm.NewAutoFSM(S0)
+	defer child.Close()
+	child.ConfigState(S0).Accept(E1, S1)
+	child.ConfigState(S1).Accept(E2, S2).OnEnter(fsm.ActionFunc(func() {
+		path = append(path, "child S1")
+	}))
+	child.ConfigState(S2).OnEnter(fsm.ActionFunc(func() {
+		path = append(path, "child S2")
+		child.Stop()
+	}))
+
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(E3, S3)
+	sm.ConfigState(S3).AutoSubmachine(child, E1, S4).OnEnter(fsm.ActionFunc(func() {
+		path = append(path, "enter S3")
+	})).OnExit(fsm.ActionFunc(func() {
+		path = append(path, "exit S3")
+	}))
+	sm.ConfigState(S4).Accept(E0, S3).OnEnter(fsm.ActionFunc(func() {
+		path = append(path, "enter S4")
+	}))
+
+	sm.Step(E3) // child waits in S1 for E2
+	sm.Step(E2) // forward to child
+	sm.Step(E0) // restart child
+	sm.Step(E2)
+
+	want := "enter S3,child S1,child S2,exit S3,enter S4," +
+		"enter S3,child S1,child S2,exit S3,enter S4"
+	if got := strings.Join(path, ","); got != want {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
+
+// a failing auto child is not completed, its error is returned
+func TestAutoSubmachineFail(t *testing.T) {
+	child := fsm.NewAutoFSM(S0)
+	defer child.Close()
+	child.ConfigState(S0).Accept(E1, S1)
+	child.ConfigState(S1).Accept(E2, S2)
+	child.ConfigState(S2).OnEnterErr(fsm.ErrActionFunc(fail))
+
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(E3, S3)
+	sm.ConfigState(S3).AutoSubmachine(child, E1, S4)
+
+	if _, err := sm.TryStep(E3); err != nil {
+		t.Fatalf("enter S3: %v", err)
+	}
+	_, err := sm.TryStep(E2)
+	if !errors.Is(err, errBoom) || sm.Current() != S3 || !errors.Is(child.Err(), errBoom) {
+		t.Errorf("got %v in %v, child %v", err, sm.Current(), child.Err())
+	}
+
+	// the child fails as it starts
+	child.ConfigState(S0).OnEnterErr(fsm.ErrActionFunc(fail)).Accept(E1, S0)
+	sm.ResetTo(S0)
+	_, err = sm.TryStep(E3)
+	if !errors.Is(err, errBoom) || sm.Current() != S3 {
+		t.Errorf("got %v in %v", err, sm.Current())
+	}
+}
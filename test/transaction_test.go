@@ -0,0 +1,139 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+var errBoom = errors.New("boom")
+
+func fail() error {
+	return errBoom
+}
+
+func TestRollback(t *testing.T) {
+	var log []string
+	undoable := func(name string, err error) fsm.ErrAction {
+		return fsm.Compensable(func() error {
+			if err == nil {
+				log = append(log, name)
+			}
+			return err
+		}, func() {
+			log = append(log, "undo "+name)
+		})
+	}
+
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).
+		AcceptAction(E1, S1, undoable("act", errBoom)).
+		AcceptAction(E2, S2, undoable("act", nil)).
+		Accept(E3, S3).
+		OnExitErr(undoable("exit", nil))
+	sm.ConfigState(S3).OnExitErr(fsm.ErrActionFunc(fail)).Accept(E0, S0)
+
+	// transition action fails, exit is compensated
+	_, err := sm.TryStep(E1)
+	if !errors.Is(err, errBoom) || sm.Current() != S0 || sm.Err() != err {
+		t.Fatalf("got %v in %v", err, sm.Current())
+	}
+	var te *fsm.TransitionError
+	if !errors.As(err, &te) || te.From != S0 || te.Event != E1 || te.To != S1 {
+		t.Errorf("got %+v", te)
+	}
+	if len(log) != 2 || log[0] != "exit" || log[1] != "undo exit" {
+		t.Errorf("got %v", log)
+	}
+
+	// exit action fails
+	sm.Step(E3)
+	if _, err := sm.TryStep(E0); err == nil || sm.Current() != S3 {
+		t.Errorf("got %v in %v", err, sm.Current())
+	}
+
+	ok, path := fsm.RunEvents(sm, E0)
+	if ok || len(path) != 1 {
+		t.Errorf("got %v %v", ok, path)
+	}
+
+	defer func() {
+		if !errors.Is(recover().(error), errBoom) {
+			t.Error("Step does not panic with the error")
+		}
+	}()
+	sm.Step(E0)
+}
+
+func TestEnterFail(t *testing.T) {
+	var log []string
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).
+		AcceptPush(E1, 1, S1).
+		Accept(E2, S2).
+		Accept(E3, S3).
+		OnExitErr(fsm.Compensable(func() error {
+			return nil
+		}, func() {
+			log = append(log, "undo exit")
+		}))
+	sm.ConfigState(S1).OnEnterErr(fsm.ErrActionFunc(fail)).OnEnterFail(fsm.FailStay)
+	sm.ConfigState(S2).OnEnterErr(fsm.ErrActionFunc(fail)).OnEnterFail(fsm.FailRevert)
+	sm.ConfigState(S3).OnEnterErr(fsm.ErrActionFunc(fail)).OnEnterFail(fsm.FailGoto, S4)
+	sm.ConfigState(S4).Accept(E0, S0).OnEnter(fsm.ActionFunc(func() {
+		if !errors.Is(sm.Err(), errBoom) {
+			t.Errorf("error state got %v", sm.Err())
+		}
+		log = append(log, "enter S4")
+	}))
+	sm.ConfigState(S1).AcceptPop(E0, 1, S0)
+
+	if _, err := sm.TryStep(E1); err == nil || sm.Current() != S1 || sm.Depth() != 1 {
+		t.Errorf("stay: got %v in %v", err, sm.Current())
+	}
+	sm.Step(E0)
+
+	if _, err := sm.TryStep(E2); err == nil || sm.Current() != S0 {
+		t.Errorf("revert: got %v in %v", err, sm.Current())
+	}
+
+	if _, err := sm.TryStep(E3); err != nil || sm.Current() != S4 {
+		t.Errorf("goto: got %v in %v", err, sm.Current())
+	}
+	if len(log) != 2 || log[0] != "undo exit" || log[1] != "enter S4" {
+		t.Errorf("got %v", log)
+	}
+}
+
+func TestAutoFail(t *testing.T) {
+	sm := fsm.NewAutoFSM(S0)
+	defer sm.Close()
+	sm.ConfigState(S0).Accept(E1, S1)
+	sm.ConfigState(S1).Accept(E2, S2).OnEnter(fsm.ActionFunc(func() {
+		sm.Feed(E2)
+	}))
+	sm.ConfigState(S2).OnEnterErr(fsm.ErrActionFunc(fail)).OnEnterFail(fsm.FailRevert)
+
+	sm.Start(E1)
+	if sm.Current() != S1 || !errors.Is(sm.Err(), errBoom) {
+		t.Errorf("got %v in %v", sm.Err(), sm.Current())
+	}
+}
+
+func TestTransitionErrorNames(t *testing.T) {
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+	sm.NameState(S0, "idle")
+	sm.NameState(S1, "busy")
+	sm.NameEvent(E1, "go")
+	sm.ConfigState(S0).AcceptAction(E1, S1, fsm.ErrActionFunc(fail))
+
+	_, err := sm.TryStep(E1)
+	if got := fmt.Sprint(err); got != "fsm: idle -go-> busy: boom" {
+		t.Errorf("got %v", got)
+	}
+}
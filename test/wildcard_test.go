@@ -0,0 +1,57 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/shory152/fsm"
+)
+
+// S0 -E1-> S1 -E2-> S2, E5 from any state except S2 goes to S5,
+// E3 from any state goes to S3.
+func TestAcceptAny(t *testing.T) {
+	var path []fsm.State
+	record := func(s fsm.State) fsm.Action {
+		return fsm.ActionFunc(func() {
+			path = append(path, s)
+		})
+	}
+
+	sm := fsm.NewStepFSM(S0)
+	defer sm.Close()
+
+	sm.ConfigState(S0).Accept(E1, S1)
+	sm.ConfigState(S1).Accept(E2, S2).Accept(E5, S4).OnEnter(record(S1))
+	sm.ConfigState(S2).Accept(E3, S1).OnEnter(record(S2))
+	sm.ConfigState(S3).OnEnter(record(S3))
+	sm.ConfigState(S4).OnEnter(record(S4))
+	sm.ConfigState(S5).Accept(E1, S1).OnEnter(record(S5))
+	sm.AcceptAnyExcept(E5, S5, S2)
+	sm.AcceptAny(E3, S3)
+
+	sm.Step(E5) // S0 -> S5 by wildcard
+	sm.Step(E1) // S5 -> S1
+	sm.Step(E5) // S1 -> S4, state-specific wins
+	sm.Step(E3) // S4 -> S3 by wildcard
+	sm.Step(E3) // S3 -> S3 by wildcard
+
+	want := []fsm.State{S5, S1, S4, S3, S3}
+	if len(path) != len(want) {
+		t.Fatalf("path %v, want %v", path, want)
+	}
+	for i := range want {
+		if path[i] != want[i] {
+			t.Fatalf("path %v, want %v", path, want)
+		}
+	}
+
+	sm2 := fsm.NewStepFSM(S2)
+	defer sm2.Close()
+	sm2.ConfigState(S2).Accept(E3, S1)
+	sm2.AcceptAnyExcept(E5, S5, S2)
+	defer func() {
+		if recover() == nil {
+			t.Error("excepted state accepted the wildcard event")
+		}
+	}()
+	sm2.Step(E5)
+}
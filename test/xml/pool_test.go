@@ -0,0 +1,96 @@
+package xml
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/shory152/fsm/xmlscan"
+)
+
+func scanBytes(s *xmlscan.Scanner) error {
+	for s.Scan() {
+		_ = s.Bytes()
+	}
+	return s.Err()
+}
+
+func TestScan(t *testing.T) {
+	want, err := scanAll(strings.NewReader(xmlstr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, r := range []io.Reader{
+		strings.NewReader(xmlstr),
+		iotest.OneByteReader(strings.NewReader(xmlstr)),
+	} {
+		s := xmlscan.Acquire(r)
+		var got []xmlscan.Token
+		for s.Scan() {
+			got = append(got, xmlscan.Token{Kind: s.Kind(), Val: string(s.Bytes()), Pos: s.Pos()})
+		}
+		if s.Err() != nil || len(got) != len(want) {
+			t.Fatalf("reader %v: got %v tokens, %v", i, len(got), s.Err())
+		}
+		for j := range want {
+			if got[j] != want[j] {
+				t.Errorf("reader %v token %v: got %v, want %v", i, j, got[j], want[j])
+			}
+		}
+		xmlscan.Release(s)
+	}
+
+	s := xmlscan.Acquire(strings.NewReader("<a></b"))
+	defer xmlscan.Release(s)
+	if scanBytes(s) == nil {
+		t.Error("syntax error is not reported")
+	}
+	s.Reset(strings.NewReader("<a></a>"))
+	if err := scanBytes(s); err != nil {
+		t.Errorf("after reset: %v", err)
+	}
+}
+
+// a Scanner reused by Reset, which does not depend on the pool keeping
+// it, as it may not under the race detector
+func TestScanAllocs(t *testing.T) {
+	r := strings.NewReader(xmlstr)
+	s := xmlscan.NewScanner(r)
+	scan := func() {
+		r.Reset(xmlstr)
+		s.Reset(r)
+		if err := scanBytes(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scan()
+	if n := testing.AllocsPerRun(100, scan); n != 0 {
+		t.Errorf("got %v allocs per scan", n)
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	r := strings.NewReader(xmlstr)
+	b.SetBytes(int64(len(xmlstr)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Reset(xmlstr)
+		s := xmlscan.Acquire(r)
+		if err := scanBytes(s); err != nil {
+			b.Fatal(err)
+		}
+		xmlscan.Release(s)
+	}
+}
+
+func BenchmarkNewScanner(b *testing.B) {
+	b.SetBytes(int64(len(xmlstr)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := scanAll(strings.NewReader(xmlstr)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
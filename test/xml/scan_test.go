@@ -0,0 +1,138 @@
+package xml
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/shory152/fsm"
+	"github.com/shory152/fsm/xmlscan"
+)
+
+func scanAll(r io.Reader) ([]xmlscan.Token, error) {
+	var tokens []xmlscan.Token
+	s := xmlscan.NewScanner(r)
+	for {
+		tk, err := s.Next()
+		if err == io.EOF {
+			return tokens, nil
+		} else if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, tk)
+	}
+}
+
+func tok(k xmlscan.Kind, val string, off int64, line, col int) xmlscan.Token {
+	return xmlscan.Token{Kind: k, Val: val, Pos: fsm.Position{Offset: off, Line: line, Column: col}}
+}
+
+func TestScanner(t *testing.T) {
+	// a reader which is not an io.RuneScanner
+	tokens, err := scanAll(iotest.OneByteReader(strings.NewReader(xmlstr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []xmlscan.Token{
+		tok(xmlscan.XML_HEAD, "xml version=1.0 encoding=UTF-8 ", 3, 2, 3),
+		tok(xmlscan.XML_TAG_OPTN, "books", 38, 3, 2),
+		tok(xmlscan.XML_COMMENT, " 2 books - - -x- -- ", 50, 4, 6),
+		tok(xmlscan.XML_TAG_OPTN, "book", 76, 5, 3),
+		tok(xmlscan.XML_PRO_KEY, "p1", 81, 5, 8),
+		tok(xmlscan.XML_PRO_VAL, "v1", 85, 5, 12),
+		tok(xmlscan.XML_PRO_KEY, "p2", 89, 5, 16),
+		tok(xmlscan.XML_PRO_VAL, "v2", 93, 5, 20),
+		tok(xmlscan.XML_TAG_OPTN, "name", 101, 6, 4),
+	}
+	for i, tk := range want {
+		if tokens[i] != tk {
+			t.Errorf("token %v: got %v, want %v", i, tokens[i], tk)
+		}
+	}
+
+	var sb strings.Builder
+	for _, tk := range tokens[len(want):] {
+		sb.WriteString(tk.Kind.String())
+		sb.WriteString(":")
+		sb.WriteString(tk.Val)
+		sb.WriteString("|")
+	}
+	rest := "XML_TEXT:\n\t\t\n\t\t 大 道 中 国 |XML_TAG_CLOSE:name|" +
+		"XML_TAG_OPTN:price|XML_TEXT: 89.00 |XML_TAG_CLOSE:price|" +
+		"XML_TAG_OPTN:author|XML_TEXT:张大中|XML_TAG_CLOSE:author|" +
+		"XML_TAG_CLOSE:book|XML_TAG_OPTN:book|" +
+		"XML_TAG_OPTN:name|XML_TEXT:小猪唏哩呼噜|XML_TAG_CLOSE:name|" +
+		"XML_TAG_OPTN:price|XML_TEXT:22.50|XML_TAG_CLOSE:price|" +
+		"XML_TAG_OPTN:author|XML_TEXT:Alex|XML_TAG_CLOSE:author|" +
+		"XML_TAG_CLOSE:book|XML_TAG_CLOSE:books|"
+	if sb.String() != rest {
+		t.Errorf("got %q\nwant %q", sb.String(), rest)
+	}
+}
+
+func TestScannerSelfClosing(t *testing.T) {
+	tokens, err := scanAll(strings.NewReader(`<a><b k='x y'/>t<c/></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, tk := range tokens {
+		got = append(got, tk.Val)
+	}
+	if strings.Join(got, ",") != "a,b,k,x y,b,t,c,c,a" {
+		t.Errorf("got %v", got)
+	}
+
+	// the close token is at '>'
+	tokens, err = scanAll(strings.NewReader(`<a x="1"/>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := tok(xmlscan.XML_TAG_CLOSE, "a", 9, 1, 10); len(tokens) != 4 || tokens[3] != want {
+		t.Errorf("got %v, want %v", tokens, want)
+	}
+}
+
+func TestScannerErrors(t *testing.T) {
+	cases := []struct {
+		xml  string
+		msg  string
+		line int
+		col  int
+	}{
+		{"<a>\n  <b c=\"1\" =>", `unexpected '='`, 2, 12},
+		{"<a>x</a>\n<?xml?>", "header not at the beginning", 2, 2},
+		{"<a><!-- x", "unexpected EOF", 1, 10},
+		{"x", `unexpected 'x'`, 1, 1},
+		{"<a>x</a b>", `unexpected 'b'`, 1, 9},
+	}
+	for _, c := range cases {
+		_, err := scanAll(strings.NewReader(c.xml))
+		var se *xmlscan.SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: got %v, want syntax error", c.xml, err)
+			continue
+		}
+		if se.Msg != c.msg || se.Pos.Line != c.line || se.Pos.Column != c.col {
+			t.Errorf("%q: got %v", c.xml, err)
+		}
+	}
+
+	// blanks before '>' of a close tag are valid
+	tokens, err := scanAll(strings.NewReader("<a>x</a >\n<b></b\n\t>"))
+	if err != nil || len(tokens) != 5 || tokens[2].Val != "a" || tokens[4].Val != "b" {
+		t.Errorf("got %v, %v", tokens, err)
+	}
+	if _, err := xmlscan.Parse(strings.NewReader("<a>x</a >")); err != nil {
+		t.Errorf("parse close tag with blank: %v", err)
+	}
+
+	ioerr := errors.New("broken")
+	_, err = scanAll(io.MultiReader(strings.NewReader("<a>"), iotest.ErrReader(ioerr)))
+	if err != ioerr {
+		t.Errorf("got %v, want %v", err, ioerr)
+	}
+}
@@ -0,0 +1,92 @@
+package xml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shory152/fsm/xmlscan"
+)
+
+func TestParseTree(t *testing.T) {
+	doc, err := xmlscan.Parse(strings.NewReader(xmlstr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Header != "xml version=1.0 encoding=UTF-8 " {
+		t.Errorf("header %q", doc.Header)
+	}
+
+	books := doc.Root
+	if books.Name != "books" || len(books.Kids) != 3 {
+		t.Fatalf("root %v with %v kids", books.Name, len(books.Kids))
+	}
+	if c := books.Kids[0]; c.Type != xmlscan.CommentNode || c.Data != " 2 books - - -x- -- " {
+		t.Errorf("comment %+v", c)
+	}
+	book := books.Kids[1]
+	if v, ok := book.Attr("p2"); !ok || v != "v2" {
+		t.Errorf("attr p2 %q %v", v, ok)
+	}
+	if name := book.Kids[0]; name.Name != "name" || strings.TrimSpace(name.Text()) != "大 道 中 国" {
+		t.Errorf("name %q", name.Text())
+	}
+	if p := books.Kids[2].Kids[1]; p.Name != "price" || p.Text() != "22.50" {
+		t.Errorf("price %q", p.Text())
+	}
+}
+
+func TestWriteTree(t *testing.T) {
+	src := `<?xml version="1.0"?>
+<!-- c -->
+<a k="1 &lt; 2" e=''>x &amp; y<b/><c>z</c><!--d--></a>
+`
+	doc, err := xmlscan.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := doc.Root.Attr("k"); v != "1 < 2" {
+		t.Errorf("attr k %q", v)
+	}
+	if txt := doc.Root.Text(); txt != "x & y" {
+		t.Errorf("text %q", txt)
+	}
+
+	var sb strings.Builder
+	if _, err := doc.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(src, "e=''", `e=""`, 1)
+	if sb.String() != want {
+		t.Errorf("got %q\nwant %q", sb.String(), want)
+	}
+	if s := doc.Root.Kids[2].String(); s != "<c>z</c>" {
+		t.Errorf("got %q", s)
+	}
+}
+
+func TestParseTreeErrors(t *testing.T) {
+	cases := []struct {
+		xml  string
+		msg  string
+		line int
+		col  int
+	}{
+		{"<a>\n <b>x</c>\n</a>", "close tag </c> does not match <b> at 2:3", 2, 8},
+		{"<a><b></b>", "element <a> is not closed", 1, 2},
+		{"<a/><b/>", "more than one root element", 1, 6},
+		{"</a>", "unexpected close tag </a>", 1, 3},
+		{"<!-- x -->", "no root element", 1, 11},
+	}
+	for _, c := range cases {
+		_, err := xmlscan.Parse(strings.NewReader(c.xml))
+		var se *xmlscan.SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: got %v, want syntax error", c.xml, err)
+			continue
+		}
+		if se.Msg != c.msg || se.Pos.Line != c.line || se.Pos.Column != c.col {
+			t.Errorf("%q: got %v", c.xml, err)
+		}
+	}
+}
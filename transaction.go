@@ -0,0 +1,173 @@
+package fsm
+
+import (
+	"fmt"
+)
+
+// Action which may fail
+type ErrAction interface {
+	Do() error
+}
+
+// ErrAction func
+type ErrActionFunc func() error
+
+func (f ErrActionFunc) Do() error {
+	return f()
+}
+
+// implemented by an ErrAction whose side effects can be undone. Undo is
+// called when a later action of the same transition fails and the
+// machine is rolled back.
+type Compensator interface {
+	Undo()
+}
+
+type compensable struct {
+	do   func() error
+	undo func()
+}
+
+func (c compensable) Do() error {
+	return c.do()
+}
+
+func (c compensable) Undo() {
+	c.undo()
+}
+
+// ErrAction do, whose side effects are undone by undo on rollback
+func Compensable(do func() error, undo func()) ErrAction {
+	return compensable{do, undo}
+}
+
+// adapts Action to ErrAction
+type noErr struct {
+	Action
+}
+
+func (a noErr) Do() error {
+	a.Action.Do()
+	return nil
+}
+
+func actionOf(a Action) ErrAction {
+	if a == nil {
+		return nil
+	}
+	return noErr{a}
+}
+
+// what to do when an enter action of a state fails
+type FailPolicy int
+
+const (
+	// stay in the state entered, the default
+	FailStay FailPolicy = iota
+	// roll back to the previous state, compensating the exit and
+	// transition actions done
+	FailRevert
+	// enter the error state, the error is reported by Err
+	FailGoto
+)
+
+// the failure of an action in the transition From -Event-> To
+type TransitionError struct {
+	From  State
+	Event Event
+	To    State
+	Err   error
+	// names in the machine failed
+	names [3]string
+}
+
+// the TransitionError with the names of fsm
+func (fsm *stateMachine) transitionError(from State, ev Event, to State, err error) *TransitionError {
+	return &TransitionError{from, ev, to, err,
+		[3]string{fsm.StateName(from), fsm.EventName(ev), fsm.StateName(to)}}
+}
+
+func (e *TransitionError) Error() string {
+	if e.names[0] == "" {
+		return fmt.Sprintf("fsm: %v -%v-> %v: %v", e.From, e.Event, e.To, e.Err)
+	}
+	return fmt.Sprintf("fsm: %s -%s-> %s: %v", e.names[0], e.names[1], e.names[2], e.Err)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Err
+}
+
+// execute act when enter this state, the failure of it is handled by the
+// policy set by OnEnterFail
+func (is *interState) OnEnterErr(act ErrAction) ConfigState {
+	is.fsm.configurable()
+	is.enterAction = is.enterAction.setDefault(act)
+	return is
+}
+
+// execute act when exit this state, the machine stays in this state if
+// it fails.
+func (is *interState) OnExitErr(act ErrAction) ConfigState {
+	is.fsm.configurable()
+	is.exitAction = is.exitAction.setDefault(act)
+	return is
+}
+
+// this state accept e, then transfer to next executing act after exiting
+// this state. the machine is rolled back to this state if act fails.
+func (is *interState) AcceptAction(e Event, next State, act ErrAction) ConfigState {
+	is.fsm.configurable()
+	is.Accept(e, next)
+	is.next[e].errAction = act
+	return is
+}
+
+// set the policy when the enter action of this state fails, errState is
+// required by FailGoto. the enter action of errState is executed, but
+// its failure is only reported.
+func (is *interState) OnEnterFail(p FailPolicy, errState ...State) ConfigState {
+	is.fsm.configurable()
+	is.failPolicy = p
+	is.errState = nil
+	if p == FailGoto {
+		if len(errState) != 1 {
+			panic("FailGoto requires an error state")
+		}
+		is.errState = is.fsm.ConfigState(errState[0]).(*interState)
+	}
+	return is
+}
+
+// the error of the last step, nil if it succeeds. a step handled by
+// FailGoto reports the error of the enter action failed.
+func (fsm *stateMachine) Err() error {
+	return fsm.lastErr
+}
+
+// undo a if it is a Compensator
+func compensate(a ErrAction) {
+	if c, ok := a.(Compensator); ok {
+		c.Undo()
+	}
+}
+
+// undo the enter, transition and exit actions done in the reverse order,
+// and restore the stack
+func (fsm *stateMachine) rollback(m stackMark, exit hookRun, act ErrAction, enter hookRun) {
+	enter.undo()
+	compensate(act)
+	exit.undo()
+	fsm.restoreStack(m)
+}
+
+// enter the error state es from is after an action fails with terr
+func (fsm *stateMachine) enterErrState(is, es *interState, ev Event, terr error) (any, error) {
+	fsm.lastErr = terr
+	fsm.currentState = es.id
+	enter := es.enterHooks(is.id, ev)
+	if err := fsm.runHooks(&enter); err != nil {
+		return nil, fsm.transitionError(is.id, ev, es.id, err)
+	}
+	return es.output, nil
+}
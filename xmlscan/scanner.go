@@ -0,0 +1,525 @@
+// Package xmlscan is a streaming xml tokenizer built on fsm.
+package xmlscan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/shory152/fsm"
+)
+
+// kind of Token
+type Kind int
+
+const (
+	_             Kind = iota
+	XML_TAG_OPTN       // <name>
+	XML_TEXT           // between openTag and closeTag
+	XML_TAG_CLOSE      // </name>
+	XML_PRO_KEY        // <xx KEY=v1>
+	XML_PRO_VAL        // <xx k1=VALUE>, without quotes
+	XML_COMMENT        // <!-- ... -->
+	XML_HEAD           // <?xml ...?>
+)
+
+var kindNames = [...]string{
+	XML_TAG_OPTN:  "XML_TAG_OPTN",
+	XML_TEXT:      "XML_TEXT",
+	XML_TAG_CLOSE: "XML_TAG_CLOSE",
+	XML_PRO_KEY:   "XML_PRO_KEY",
+	XML_PRO_VAL:   "XML_PRO_VAL",
+	XML_COMMENT:   "XML_COMMENT",
+	XML_HEAD:      "XML_HEAD",
+}
+
+func (k Kind) String() string {
+	if k > 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+type Token struct {
+	Kind Kind
+	Val  string
+	Pos  fsm.Position // position of the first rune of Val
+}
+
+// error of malformed xml
+type SyntaxError struct {
+	Msg string
+	Pos fsm.Position
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("xmlscan: syntax error at %v: %s", e.Pos, e.Msg)
+}
+
+// states of the scanner
+const (
+	_      fsm.State = iota
+	sStart           // start
+	sLt              // recv '<'
+	sH1              // header <?
+	sH2              // header <?xml ... ?
+	sH3              // header <?xml ... ?>
+	sCt1             // close tag '</'
+	sCt2             // close tag '</xxx>'
+	sCt3             // blank in close tag '</xxx '
+	sOt1             // open tag <xxx
+	sOt2             // open tag <xxx>
+	sOt3             // blank in open tag
+	sTt              // text
+	sPt1             // key of k=v
+	sPt2             // value of k=v
+	sPq              // "value"
+	sPa              // 'value'
+	sPe              // end of quoted value
+	sCm1             // comment <!
+	sCm2             // comment <!-
+	sCm3             // comment <!--
+	sCm4             // comment <!-- ... -
+	sCm5             // comment <!-- ... --
+	sCm6             // comment <!-- ... -->
+	sSc              // self-closing tag <xxx/
+	sEnd             // EOF
+	sErr             // read error
+	sSerr            // syntax error
+	sUeof            // unexpected EOF
+)
+
+// events of the scanner
+const (
+	_      fsm.Event = iota
+	eSpace           // ' '
+	eLine            // \t \n \r
+	eLt              // '<'
+	eGt              // '>'
+	eEq              // '='
+	eQes             // '?'
+	eSl              // '/'
+	eGth             // '!'
+	eDesh            // '-'
+	eQuot            // '"'
+	eApos            // '\''
+	eOc              // other char
+	eEof             // EOF
+	eErr             // read error
+)
+
+func classify(c rune) fsm.Event {
+	switch c {
+	case ' ':
+		return eSpace
+	case '\t', '\n', '\r':
+		return eLine
+	case '<':
+		return eLt
+	case '>':
+		return eGt
+	case '=':
+		return eEq
+	case '?':
+		return eQes
+	case '/':
+		return eSl
+	case '!':
+		return eGth
+	case '-':
+		return eDesh
+	case '"':
+		return eQuot
+	case '\'':
+		return eApos
+	}
+	return eOc
+}
+
+// Scanner reads xml tokens from a reader
+type Scanner struct {
+	sm     fsm.InputFSM
+	br     *bufio.Reader
+	val    []byte // value of the token scanning
+	start  fsm.Position
+	tag    []byte // name of the last open tag
+	kind   Kind
+	buf    []byte // value of the token scanned
+	pos    fsm.Position
+	ready  bool
+	tokens int
+	err    error
+}
+
+var scanners = fsm.NewPool(func() *Scanner {
+	return NewScanner(nil)
+}, nil)
+
+func NewScanner(r io.Reader) *Scanner {
+	s := &Scanner{}
+	s.config(s.runeScanner(r))
+	return s
+}
+
+// a Scanner reading r from a pool, which is returned by Release
+func Acquire(r io.Reader) *Scanner {
+	s := scanners.Acquire()
+	s.Reset(r)
+	return s
+}
+
+// return s got by Acquire to the pool, s must not be used after
+func Release(s *Scanner) {
+	s.Reset(nil)
+	scanners.Release(s)
+}
+
+// scan r from the beginning, the configured machine is reused
+func (s *Scanner) Reset(r io.Reader) {
+	s.sm.ResetInput(s.runeScanner(r))
+	s.val = s.val[:0]
+	s.start = fsm.Position{}
+	s.tag = s.tag[:0]
+	s.kind = 0
+	s.buf = s.buf[:0]
+	s.pos = fsm.Position{}
+	s.ready = false
+	s.tokens = 0
+	s.err = nil
+}
+
+// wrap r in the bufio.Reader of s if it is not an io.RuneScanner
+func (s *Scanner) runeScanner(r io.Reader) io.RuneScanner {
+	if rs, ok := r.(io.RuneScanner); ok {
+		return rs
+	}
+	if r == nil {
+		// drop the reader released
+		if s.br != nil {
+			s.br.Reset(nil)
+		}
+		return nil
+	}
+	if s.br == nil {
+		s.br = bufio.NewReader(r)
+	} else {
+		s.br.Reset(r)
+	}
+	return s.br
+}
+
+// scan the next token
+func (s *Scanner) scan() error {
+	for !s.ready {
+		if s.err != nil {
+			return s.err
+		}
+		if err := s.sm.Advance(); err == io.EOF {
+			if s.err == nil {
+				s.err = &SyntaxError{"unexpected EOF", s.sm.Pos()}
+			}
+		} else if err != nil {
+			s.err = err
+		}
+	}
+	s.ready = false
+	return nil
+}
+
+// return the next token, io.EOF at the end of input, or the error
+// which stops scanning, such as *SyntaxError.
+func (s *Scanner) Next() (Token, error) {
+	if err := s.scan(); err != nil {
+		return Token{}, err
+	}
+	return Token{s.kind, string(s.buf), s.pos}, nil
+}
+
+// scan the next token without allocating, which is got by Kind, Bytes
+// and Pos. it returns false at the end of input or an error, see Err.
+func (s *Scanner) Scan() bool {
+	return s.scan() == nil
+}
+
+// the kind of the token scanned
+func (s *Scanner) Kind() Kind {
+	return s.kind
+}
+
+// the value of the token scanned, valid until the next Scan
+func (s *Scanner) Bytes() []byte {
+	return s.buf
+}
+
+// position of the token scanned
+func (s *Scanner) Pos() fsm.Position {
+	return s.pos
+}
+
+// the error which stops Scan, nil at the end of input
+func (s *Scanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+// keep the current rune in the value of the token
+func (s *Scanner) keep() {
+	if len(s.val) == 0 {
+		s.start = s.sm.Pos()
+	}
+	s.val = utf8.AppendRune(s.val, s.sm.Rune())
+}
+
+func (s *Scanner) emit(k Kind) {
+	if len(s.val) == 0 {
+		s.start = s.sm.Pos()
+	}
+	s.kind = k
+	s.pos = s.start
+	s.buf, s.val = s.val, s.buf[:0]
+	if k == XML_TAG_OPTN {
+		s.tag = append(s.tag[:0], s.buf...)
+	}
+	s.ready = true
+	s.tokens++
+}
+
+func (s *Scanner) syntaxError(msg string) {
+	s.err = &SyntaxError{msg, s.sm.Pos()}
+	s.sm.Stop()
+}
+
+func (s *Scanner) config(rs io.RuneScanner) {
+	sm := fsm.NewInputFSM(sStart, rs, classify)
+	s.sm = sm
+	sm.OnEOF(eEof)
+	sm.OnReadError(eErr)
+
+	keep := fsm.ActionFunc(s.keep)
+	skip := fsm.ActionFunc(func() {})
+	drop := fsm.ActionFunc(func() {
+		s.val = s.val[:0]
+	})
+	emit := func(k Kind) fsm.Action {
+		return fsm.ActionFunc(func() {
+			s.emit(k)
+		})
+	}
+
+	// read error and unexpected EOF in any state, syntax error in
+	// the states configured Otherwise(sSerr).
+	sm.AcceptAny(eErr, sErr)
+	sm.AcceptAny(eEof, sUeof)
+
+	sm.ConfigState(sStart).
+		Accept(eSpace, sStart).
+		Accept(eLine, sStart).
+		Accept(eLt, sLt).
+		Accept(eEof, sEnd).
+		Otherwise(sSerr)
+
+	sm.ConfigState(sLt).
+		Accept(eQes, sH1).
+		Accept(eSl, sCt1).
+		Accept(eGth, sCm1).
+		Accept(eOc, sOt1).
+		Otherwise(sSerr)
+
+	// header
+	sm.ConfigState(sH1).
+		Accept(eQes, sH2).
+		Accept(eLt, sSerr).
+		Accept(eGt, sSerr).
+		Otherwise(sH1).
+		OnEnter(keep).
+		OnEnterFrom(sLt, fsm.ActionFunc(func() {
+			if s.tokens > 0 {
+				s.syntaxError("header not at the beginning")
+			}
+		})).
+		OnEnterFrom(sH2, fsm.ActionFunc(func() {
+			s.val = append(s.val, '?')
+			s.keep()
+		}))
+	sm.ConfigState(sH2).
+		Accept(eGt, sH3).
+		Otherwise(sH1).
+		OnExitEvent(eGt, emit(XML_HEAD))
+	sm.ConfigState(sH3).
+		Accept(eSpace, sH3).
+		Accept(eLine, sH3).
+		Accept(eLt, sLt).
+		Accept(eEof, sEnd).
+		Otherwise(sSerr)
+
+	// close tag
+	sm.ConfigState(sCt1).
+		Accept(eGt, sCt2).
+		Accept(eLt, sSerr).
+		Accept(eSpace, sCt3).
+		Accept(eLine, sCt3).
+		Otherwise(sCt1).
+		OnEnter(keep).
+		OnEnterFrom(sLt, skip).
+		OnExitEvent(eGt, emit(XML_TAG_CLOSE))
+	sm.ConfigState(sCt3).
+		Accept(eSpace, sCt3).
+		Accept(eLine, sCt3).
+		Accept(eGt, sCt2).
+		Otherwise(sSerr).
+		OnExitEvent(eGt, emit(XML_TAG_CLOSE))
+	sm.ConfigState(sCt2).
+		Accept(eSpace, sCt2).
+		Accept(eLine, sCt2).
+		Accept(eLt, sLt).
+		Accept(eEof, sEnd).
+		Otherwise(sTt).
+		OnEnter(keep).
+		OnEnterFrom(sCt1, skip).
+		OnEnterFrom(sCt3, skip).
+		OnEnterFrom(sSc, skip).
+		OnExitEvent(eLt, drop).
+		OnExitEvent(eEof, drop)
+	sm.ConfigState(sSc).
+		Accept(eGt, sCt2).
+		Otherwise(sSerr).
+		OnExitEvent(eGt, fsm.ActionFunc(func() {
+			s.start = s.sm.Pos()
+			s.val = append(s.val, s.tag...)
+			s.emit(XML_TAG_CLOSE)
+		}))
+
+	// open tag, properties and text
+	sm.ConfigState(sOt1).
+		Accept(eGt, sOt2).
+		Accept(eSpace, sOt3).
+		Accept(eLine, sOt3).
+		Accept(eSl, sSc).
+		Accept(eLt, sSerr).
+		Otherwise(sOt1).
+		OnEnter(keep).
+		OnExitEvent(eGt, emit(XML_TAG_OPTN)).
+		OnExitEvent(eSpace, emit(XML_TAG_OPTN)).
+		OnExitEvent(eLine, emit(XML_TAG_OPTN)).
+		OnExitEvent(eSl, emit(XML_TAG_OPTN))
+	// blanks after an open tag are text only if followed by other chars
+	sm.ConfigState(sOt2).
+		Accept(eSpace, sOt2).
+		Accept(eLine, sOt2).
+		Accept(eLt, sLt).
+		Accept(eEof, sEnd).
+		Otherwise(sTt).
+		OnEnter(keep).
+		OnEnterFrom(sOt1, skip).
+		OnEnterFrom(sOt3, skip).
+		OnEnterFrom(sPt2, skip).
+		OnEnterFrom(sPe, skip).
+		OnExitEvent(eLt, drop).
+		OnExitEvent(eEof, drop)
+	sm.ConfigState(sOt3).
+		Accept(eSpace, sOt3).
+		Accept(eLine, sOt3).
+		Accept(eGt, sOt2).
+		Accept(eSl, sSc).
+		Accept(eOc, sPt1).
+		Otherwise(sSerr)
+	sm.ConfigState(sTt).
+		Accept(eLt, sLt).
+		Otherwise(sTt).
+		OnEnter(keep).
+		OnExitEvent(eLt, emit(XML_TEXT))
+	sm.ConfigState(sPt1).
+		Accept(eEq, sPt2).
+		Accept(eOc, sPt1).
+		Accept(eDesh, sPt1).
+		Otherwise(sSerr).
+		OnEnter(keep).
+		OnExitEvent(eEq, emit(XML_PRO_KEY))
+	sm.ConfigState(sPt2).
+		Accept(eQuot, sPq).
+		Accept(eApos, sPa).
+		Accept(eSpace, sOt3).
+		Accept(eLine, sOt3).
+		Accept(eGt, sOt2).
+		Accept(eLt, sSerr).
+		Otherwise(sPt2).
+		OnEnter(keep).
+		OnEnterFrom(sPt1, skip).
+		OnExitEvent(eSpace, emit(XML_PRO_VAL)).
+		OnExitEvent(eLine, emit(XML_PRO_VAL)).
+		OnExitEvent(eGt, emit(XML_PRO_VAL))
+	sm.ConfigState(sPq).
+		Accept(eQuot, sPe).
+		Otherwise(sPq).
+		OnEnter(keep).
+		OnEnterFrom(sPt2, skip).
+		OnExitEvent(eQuot, emit(XML_PRO_VAL))
+	sm.ConfigState(sPa).
+		Accept(eApos, sPe).
+		Otherwise(sPa).
+		OnEnter(keep).
+		OnEnterFrom(sPt2, skip).
+		OnExitEvent(eApos, emit(XML_PRO_VAL))
+	sm.ConfigState(sPe).
+		Accept(eSpace, sOt3).
+		Accept(eLine, sOt3).
+		Accept(eGt, sOt2).
+		Accept(eSl, sSc).
+		Otherwise(sSerr)
+
+	// comment
+	sm.ConfigState(sCm1).
+		Accept(eDesh, sCm2).
+		Otherwise(sSerr)
+	sm.ConfigState(sCm2).
+		Accept(eDesh, sCm3).
+		Otherwise(sSerr)
+	sm.ConfigState(sCm3).
+		Accept(eDesh, sCm4).
+		Accept(eLt, sSerr).
+		Accept(eGt, sSerr).
+		Otherwise(sCm3).
+		OnEnter(keep).
+		OnEnterFrom(sCm2, skip).
+		OnEnterFrom(sCm4, fsm.ActionFunc(func() {
+			s.val = append(s.val, '-')
+			s.keep()
+		})).
+		OnEnterFrom(sCm5, fsm.ActionFunc(func() {
+			s.val = append(s.val, "--"...)
+			s.keep()
+		}))
+	sm.ConfigState(sCm4).
+		Accept(eDesh, sCm5).
+		Otherwise(sCm3)
+	sm.ConfigState(sCm5).
+		Accept(eGt, sCm6).
+		Otherwise(sCm3).
+		OnExitEvent(eGt, emit(XML_COMMENT))
+	sm.ConfigState(sCm6).
+		Accept(eSpace, sCm6).
+		Accept(eLine, sCm6).
+		Accept(eLt, sLt).
+		Accept(eEof, sEnd).
+		Otherwise(sSerr)
+
+	// end
+	sm.ConfigState(sEnd).OnEnter(fsm.ActionFunc(func() {
+		s.err = io.EOF
+		sm.Stop()
+	}))
+	sm.ConfigState(sErr).OnEnter(fsm.ActionFunc(func() {
+		s.err = sm.Err()
+		sm.Stop()
+	}))
+	sm.ConfigState(sSerr).OnEnter(fsm.ActionFunc(func() {
+		s.syntaxError(fmt.Sprintf("unexpected %q", sm.Rune()))
+	}))
+	sm.ConfigState(sUeof).OnEnter(fsm.ActionFunc(func() {
+		s.syntaxError("unexpected EOF")
+	}))
+	sm.Compile()
+}
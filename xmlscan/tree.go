@@ -0,0 +1,232 @@
+package xmlscan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shory152/fsm"
+)
+
+// type of Node
+type NodeType int
+
+const (
+	_           NodeType = iota
+	ElementNode          // <name k=v>...</name>
+	TextNode             // text in an element
+	CommentNode          // <!-- ... -->
+)
+
+// attribute of an element
+type Attr struct {
+	Name  string
+	Value string
+}
+
+// Node of the xml tree. Name and Attrs are set for an element,
+// Data for a text or comment.
+type Node struct {
+	Type  NodeType
+	Name  string
+	Attrs []Attr
+	Data  string
+	Kids  []*Node
+	Pos   fsm.Position
+}
+
+// the value of the attribute name, and whether it exists
+func (n *Node) Attr(name string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// the concatenated text kids of an element
+func (n *Node) Text() string {
+	var sb strings.Builder
+	for _, k := range n.Kids {
+		if k.Type == TextNode {
+			sb.WriteString(k.Data)
+		}
+	}
+	return sb.String()
+}
+
+// the xml document: the header, and the root element with the
+// comments around it in Nodes.
+type Document struct {
+	Header string // content of <?...?>
+	Nodes  []*Node
+	Root   *Node
+}
+
+var (
+	unescaper = strings.NewReplacer(
+		"&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&amp;", "&")
+	escaper = strings.NewReplacer(
+		"<", "&lt;", ">", "&gt;", `"`, "&quot;", "&", "&amp;")
+)
+
+// parse the xml from r into a Document. the predefined entities are
+// unescaped in texts and attribute values. a *SyntaxError is returned
+// for malformed xml, such as mismatched close tags.
+func Parse(r io.Reader) (*Document, error) {
+	s := Acquire(r)
+	defer Release(s)
+	doc := &Document{}
+	var stack []*Node
+
+	add := func(n *Node) error {
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			parent.Kids = append(parent.Kids, n)
+			return nil
+		}
+		switch {
+		case n.Type == TextNode:
+			return &SyntaxError{"text out of the root element", n.Pos}
+		case n.Type == ElementNode && doc.Root != nil:
+			return &SyntaxError{"more than one root element", n.Pos}
+		case n.Type == ElementNode:
+			doc.Root = n
+		}
+		doc.Nodes = append(doc.Nodes, n)
+		return nil
+	}
+
+	for {
+		tk, err := s.Next()
+		if err == io.EOF {
+			if len(stack) > 0 {
+				top := stack[len(stack)-1]
+				return nil, &SyntaxError{fmt.Sprintf("element <%v> is not closed", top.Name), top.Pos}
+			}
+			if doc.Root == nil {
+				return nil, &SyntaxError{"no root element", s.sm.Pos()}
+			}
+			return doc, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch tk.Kind {
+		case XML_HEAD:
+			doc.Header = tk.Val
+		case XML_TAG_OPTN:
+			n := &Node{Type: ElementNode, Name: tk.Val, Pos: tk.Pos}
+			if err := add(n); err != nil {
+				return nil, err
+			}
+			stack = append(stack, n)
+		case XML_PRO_KEY:
+			top := stack[len(stack)-1]
+			top.Attrs = append(top.Attrs, Attr{Name: tk.Val})
+		case XML_PRO_VAL:
+			top := stack[len(stack)-1]
+			top.Attrs[len(top.Attrs)-1].Value = unescaper.Replace(tk.Val)
+		case XML_TEXT:
+			n := &Node{Type: TextNode, Data: unescaper.Replace(tk.Val), Pos: tk.Pos}
+			if err := add(n); err != nil {
+				return nil, err
+			}
+		case XML_COMMENT:
+			if err := add(&Node{Type: CommentNode, Data: tk.Val, Pos: tk.Pos}); err != nil {
+				return nil, err
+			}
+		case XML_TAG_CLOSE:
+			if len(stack) == 0 {
+				return nil, &SyntaxError{fmt.Sprintf("unexpected close tag </%v>", tk.Val), tk.Pos}
+			}
+			top := stack[len(stack)-1]
+			if top.Name != tk.Val {
+				return nil, &SyntaxError{
+					fmt.Sprintf("close tag </%v> does not match <%v> at %v", tk.Val, top.Name, top.Pos),
+					tk.Pos}
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// serialize the document as xml
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	bw := newCountWriter(w)
+	if d.Header != "" {
+		bw.WriteString("<?" + d.Header + "?>\n")
+	}
+	for _, n := range d.Nodes {
+		n.write(bw)
+		bw.WriteString("\n")
+	}
+	return bw.flush()
+}
+
+// serialize the node as xml
+func (n *Node) WriteTo(w io.Writer) (int64, error) {
+	bw := newCountWriter(w)
+	n.write(bw)
+	return bw.flush()
+}
+
+func (n *Node) String() string {
+	var sb strings.Builder
+	n.WriteTo(&sb)
+	return sb.String()
+}
+
+func (n *Node) write(w *countWriter) {
+	switch n.Type {
+	case TextNode:
+		escaper.WriteString(w, n.Data)
+	case CommentNode:
+		w.WriteString("<!--" + n.Data + "-->")
+	case ElementNode:
+		w.WriteString("<" + n.Name)
+		for _, a := range n.Attrs {
+			w.WriteString(" " + a.Name + `="`)
+			escaper.WriteString(w, a.Value)
+			w.WriteString(`"`)
+		}
+		if len(n.Kids) == 0 {
+			w.WriteString("/>")
+			return
+		}
+		w.WriteString(">")
+		for _, k := range n.Kids {
+			k.write(w)
+		}
+		w.WriteString("</" + n.Name + ">")
+	}
+}
+
+// buffered writer counting the bytes written
+type countWriter struct {
+	*bufio.Writer
+	cw *counter
+}
+
+type counter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newCountWriter(w io.Writer) *countWriter {
+	c := &counter{w: w}
+	return &countWriter{bufio.NewWriter(c), c}
+}
+
+func (w *countWriter) flush() (int64, error) {
+	err := w.Flush()
+	return w.cw.n, err
+}